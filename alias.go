@@ -0,0 +1,194 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"go.yaml.in/yaml/v3"
+)
+
+const mergeTag = "!!merge"
+
+// resolveAliases returns the given node with all aliases and merge keys resolved.
+//
+// Nodes are only copied when needed. The content of an anchor is copied for each
+// alias that refers to it, so that errors can report the alias the node was
+// expanded from, in addition to the location of the anchor. The number of
+// copied nodes is bounded by checkLimits, which must be called first.
+func (u *unmarshaler) resolveAliases(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		if node.Alias == nil {
+			return node
+		}
+		resolved := u.resolveAnchor(node.Alias)
+		if resolved == nil {
+			u.addErrorf(node, "alias *%s refers to its own anchor", node.Value)
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: node.Line, Column: node.Column}
+		}
+		return u.copyAlias(resolved, node)
+	}
+	if node.Anchor != "" {
+		return u.resolveAnchor(node)
+	}
+	return u.resolveContent(node)
+}
+
+// resolveAnchor resolves the given anchored node, at most once.
+//
+// Returns nil if the node is being resolved, because it contains an alias to
+// itself.
+func (u *unmarshaler) resolveAnchor(node *yaml.Node) *yaml.Node {
+	if resolved, ok := u.anchors[node]; ok {
+		return resolved
+	}
+	if u.anchors == nil {
+		u.anchors = make(map[*yaml.Node]*yaml.Node)
+	}
+	u.anchors[node] = nil
+	resolved := u.resolveContent(node)
+	u.anchors[node] = resolved
+	return resolved
+}
+
+// resolveContent resolves the aliases and merge keys in the content of the given
+// node.
+func (u *unmarshaler) resolveContent(node *yaml.Node) *yaml.Node {
+	var content []*yaml.Node
+	for i, child := range node.Content {
+		resolved := u.resolveAliases(child)
+		if resolved != child && content == nil {
+			content = make([]*yaml.Node, len(node.Content))
+			copy(content, node.Content[:i])
+		}
+		if content != nil {
+			content[i] = resolved
+		}
+	}
+	result := node
+	if content != nil {
+		result = u.copyNode(node)
+		result.Content = content
+	}
+	if result.Kind == yaml.MappingNode {
+		return u.resolveMergeKeys(result)
+	}
+	return result
+}
+
+// resolveMergeKeys replaces `<<` merge keys in the given mapping with the entries
+// of the merged mappings.
+//
+// Keys in the mapping itself take precedence over merged keys, and keys of
+// earlier merged mappings take precedence over later ones.
+func (u *unmarshaler) resolveMergeKeys(node *yaml.Node) *yaml.Node {
+	explicit := make(map[string]bool)
+	hasMerge := false
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		switch {
+		case isMergeKey(keyNode):
+			hasMerge = true
+		case keyNode.Kind == yaml.ScalarNode:
+			explicit[keyNode.Value] = true
+		}
+	}
+	if !hasMerge {
+		return node
+	}
+	result := u.copyNode(node)
+	result.Content = make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if !isMergeKey(keyNode) {
+			result.Content = append(result.Content, keyNode, valueNode)
+			continue
+		}
+		for _, source := range u.findMergeSources(valueNode) {
+			for j := 0; j+1 < len(source.Content); j += 2 {
+				mergedKey := source.Content[j]
+				if mergedKey.Kind == yaml.ScalarNode {
+					if explicit[mergedKey.Value] {
+						continue // Overridden.
+					}
+					explicit[mergedKey.Value] = true
+				}
+				result.Content = append(result.Content, mergedKey, source.Content[j+1])
+			}
+		}
+	}
+	return result
+}
+
+// findMergeSources returns the mappings referenced by the value of a merge key.
+func (u *unmarshaler) findMergeSources(node *yaml.Node) []*yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return []*yaml.Node{node}
+	case yaml.SequenceNode:
+		sources := make([]*yaml.Node, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.MappingNode {
+				u.addErrorf(item, "expected mapping for merge key, got %v", getNodeKind(item.Kind))
+				continue
+			}
+			sources = append(sources, item)
+		}
+		return sources
+	default:
+		u.addErrorf(node, "expected mapping or sequence of mappings for merge key, got %v", getNodeKind(node.Kind))
+		return nil
+	}
+}
+
+// copyAlias returns a deep copy of the given resolved anchor, recording the alias
+// it was expanded from.
+func (u *unmarshaler) copyAlias(node *yaml.Node, alias *yaml.Node) *yaml.Node {
+	result := u.copyNode(node)
+	if u.aliasSites == nil {
+		u.aliasSites = make(map[*yaml.Node][]*yaml.Node)
+	}
+	sites := make([]*yaml.Node, 0, len(u.aliasSites[node])+1)
+	sites = append(sites, u.aliasSites[node]...)
+	u.aliasSites[result] = append(sites, alias)
+	if len(node.Content) > 0 {
+		result.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			result.Content[i] = u.copyAlias(child, alias)
+		}
+	}
+	return result
+}
+
+// copyNode returns a shallow copy of the given node, which keeps the aliases the
+// node was expanded from and the file it was included from.
+func (u *unmarshaler) copyNode(node *yaml.Node) *yaml.Node {
+	result := *node
+	u.copySources(node, &result)
+	return &result
+}
+
+// copySources records that the given copy was expanded from the same aliases,
+// and included from the same file, as the given node.
+func (u *unmarshaler) copySources(node *yaml.Node, copied *yaml.Node) {
+	if sites, ok := u.aliasSites[node]; ok {
+		u.aliasSites[copied] = sites
+	}
+	if file, ok := u.sourceFiles[node]; ok {
+		u.sourceFiles[copied] = file
+	}
+}
+
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == mergeTag
+}
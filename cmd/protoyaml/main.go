@@ -0,0 +1,670 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command protoyaml validates and formats YAML files against a Protobuf message
+// type, and converts messages between YAML, JSON, binary and text formats.
+//
+// Message types are loaded from a FileDescriptorSet or a Buf image, such as
+// one written by `buf build -o image.binpb`:
+//
+//	protoyaml validate -d image.binpb -t acme.v1.Config config.yaml
+//	protoyaml fmt -d image.binpb -t acme.v1.Config -w config.yaml
+//	protoyaml lint -d image.binpb -config protoyaml-lint.yaml
+//	protoyaml convert -d image.binpb -t acme.v1.Config -o config.json config.yaml
+//	protoyaml lsp -d image.binpb -config protoyaml-lint.yaml -proto-root proto
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"buf.build/go/protoyaml"
+	"buf.build/go/protoyaml/lint"
+	"buf.build/go/protoyaml/lsp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const usage = `Usage: protoyaml <command> [flags] [file...]
+
+Commands:
+  validate  Validate YAML files, writing diagnostics for each error
+  fmt       Format YAML files in canonical form
+  lint      Validate the YAML files of a project, as mapped by a config file
+  convert   Convert a message between formats
+  lsp       Run a language server for the YAML files of a project, over stdio
+
+Run 'protoyaml <command> -h' for the flags of a command.
+`
+
+// The formats of messages, named as in `buf convert`.
+const (
+	formatBinary = "binpb"
+	formatJSON   = "json"
+	formatText   = "txtpb"
+	formatYAML   = "yaml"
+)
+
+// errFailed is returned when diagnostics have already been written.
+var errFailed = errors.New("")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if errString := err.Error(); errString != "" {
+			_, _ = fmt.Fprintln(os.Stderr, errString)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		_, _ = io.WriteString(stderr, usage)
+		return errFailed
+	}
+	switch args[0] {
+	case "validate":
+		return runValidate(args[1:], stdin, stdout, stderr)
+	case "fmt":
+		return runFormat(args[1:], stdin, stdout, stderr)
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "convert":
+		return runConvert(args[1:], stdin, stdout, stderr)
+	case "lsp":
+		return runLSP(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		_, _ = io.WriteString(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+}
+
+// typeFlags are the flags that select the message type.
+type typeFlags struct {
+	descriptors string
+	messageName string
+}
+
+func (f *typeFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.descriptors, "d", "", "the `file` containing the FileDescriptorSet or Buf image that defines the message type, in binary or JSON (.json) format")
+	flags.StringVar(&f.messageName, "t", "", "the full `name` of the message type")
+}
+
+// load returns the message type and a resolver for the types in the
+// descriptor set.
+func (f *typeFlags) load() (protoreflect.MessageType, *protoregistry.Types, error) {
+	if f.descriptors == "" || f.messageName == "" {
+		return nil, nil, errors.New("the -d and -t flags are required")
+	}
+	types, err := loadTypes(f.descriptors)
+	if err != nil {
+		return nil, nil, err
+	}
+	msgType, err := types.FindMessageByName(protoreflect.FullName(f.messageName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("message type %s: %w", f.messageName, err)
+	}
+	return msgType, types, nil
+}
+
+// loadTypes returns the types in the given FileDescriptorSet or Buf image.
+func loadTypes(descriptors string) (*protoregistry.Types, error) {
+	files, err := loadFiles(descriptors)
+	if err != nil {
+		return nil, err
+	}
+	types, err := newTypes(files)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", descriptors, err)
+	}
+	return types, nil
+}
+
+// loadFiles returns the files in the given FileDescriptorSet or Buf image.
+func loadFiles(descriptors string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(descriptors)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if strings.EqualFold(filepath.Ext(descriptors), ".json") {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, set)
+	} else {
+		// Buf images are wire compatible with FileDescriptorSet.
+		err = proto.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, set)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", descriptors, err)
+	}
+	files, err := newFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", descriptors, err)
+	}
+	return files, nil
+}
+
+// newFiles builds the files of the given set. Imports that are not in the set,
+// such as the well-known types of images built with --exclude-imports, are
+// resolved from protoregistry.GlobalFiles.
+func newFiles(set *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	byPath := make(map[string]*descriptorpb.FileDescriptorProto, len(set.GetFile()))
+	for _, file := range set.GetFile() {
+		byPath[file.GetName()] = file
+	}
+	files := &protoregistry.Files{}
+	var register func(path string) error
+	register = func(path string) error {
+		if _, err := files.FindFileByPath(path); err == nil {
+			return nil // Already registered.
+		}
+		fileProto, ok := byPath[path]
+		if !ok {
+			file, err := protoregistry.GlobalFiles.FindFileByPath(path)
+			if err != nil {
+				return fmt.Errorf("missing file %s", path)
+			}
+			return files.RegisterFile(file)
+		}
+		for _, dep := range fileProto.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		file, err := protodesc.NewFile(fileProto, files)
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(file)
+	}
+	for _, file := range set.GetFile() {
+		if err := register(file.GetName()); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// newTypes registers the message and extension types of the given files.
+// Unlike dynamicpb.Types, the result can list its message types.
+func newTypes(files *protoregistry.Files) (*protoregistry.Types, error) {
+	types := &protoregistry.Types{}
+	var err error
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		err = registerTypes(types, file.Messages(), file.Extensions())
+		return err == nil
+	})
+	return types, err
+}
+
+func registerTypes(types *protoregistry.Types, messages protoreflect.MessageDescriptors, extensions protoreflect.ExtensionDescriptors) error {
+	for i := range extensions.Len() {
+		if err := types.RegisterExtension(dynamicpb.NewExtensionType(extensions.Get(i))); err != nil {
+			return err
+		}
+	}
+	for i := range messages.Len() {
+		message := messages.Get(i)
+		if err := types.RegisterMessage(dynamicpb.NewMessageType(message)); err != nil {
+			return err
+		}
+		if err := registerTypes(types, message.Messages(), message.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var typeFlags typeFlags
+	typeFlags.register(flags)
+	format := flags.String("format", "text", "the `format` of diagnostics: text, json, sarif or github")
+	allowPartial := flags.Bool("allow-partial", false, "allow missing required fields")
+	discardUnknown := flags.Bool("discard-unknown", false, "allow unknown fields")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	writeDiagnostics, err := getDiagnosticsWriter(*format)
+	if err != nil {
+		return err
+	}
+	msgType, types, err := typeFlags.load()
+	if err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var diagnostics protoyaml.ErrorList
+	for _, path := range paths {
+		errs, err := validateFile(path, stdin, msgType, protoyaml.UnmarshalOptions{
+			Path:           path,
+			Resolver:       types,
+			AllowPartial:   *allowPartial,
+			DiscardUnknown: *discardUnknown,
+		})
+		if err != nil {
+			return err
+		}
+		diagnostics = append(diagnostics, errs...)
+	}
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	if err := writeDiagnostics(diagnostics, stdout); err != nil {
+		return err
+	}
+	return errFailed
+}
+
+// validateFile unmarshals each document in the given file, and returns the
+// errors found.
+func validateFile(path string, stdin io.Reader, msgType protoreflect.MessageType, options protoyaml.UnmarshalOptions) (protoyaml.ErrorList, error) {
+	input, closeInput, err := openInput(path, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	if path == "-" {
+		options.Path = "<stdin>"
+	}
+	decoder := protoyaml.NewDecoder(input, options)
+	var result protoyaml.ErrorList
+	for {
+		err := decoder.Decode(msgType.New().Interface())
+		var errs protoyaml.ErrorList
+		switch {
+		case errors.Is(err, io.EOF):
+			return result, nil
+		case errors.As(err, &errs):
+			result = append(result, errs...)
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", options.Path, err)
+		}
+	}
+}
+
+func runLint(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	descriptors := flags.String("d", "", "the `file` containing the FileDescriptorSet or Buf image that defines the message types, in binary or JSON (.json) format")
+	configPath := flags.String("config", "protoyaml-lint.yaml", "the config `file` that maps file patterns to message types, relative to the project directory")
+	format := flags.String("format", "text", "the `format` of diagnostics: text, json, sarif or github")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if *descriptors == "" {
+		return errors.New("the -d flag is required")
+	}
+	if flags.NArg() > 1 {
+		return errors.New("lint takes at most one project directory")
+	}
+	writeDiagnostics, err := getDiagnosticsWriter(*format)
+	if err != nil {
+		return err
+	}
+	types, err := loadTypes(*descriptors)
+	if err != nil {
+		return err
+	}
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	fsys := os.DirFS(dir)
+	config, err := lint.LoadConfig(fsys, *configPath)
+	if err != nil {
+		return err
+	}
+	diagnostics, err := lint.Linter{Config: config, Resolver: types}.Lint(context.Background(), fsys)
+	if err != nil {
+		return err
+	}
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	if err := writeDiagnostics(diagnostics, stdout); err != nil {
+		return err
+	}
+	return errFailed
+}
+
+func runLSP(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	descriptors := flags.String("d", "", "the `file` containing the FileDescriptorSet or Buf image that defines the message types, in binary or JSON (.json) format")
+	configPath := flags.String("config", "protoyaml-lint.yaml", "the config `file` that maps file patterns to message types, relative to the project directory")
+	protoRoot := flags.String("proto-root", "", "the `directory` of the .proto files of the message types, for go-to-definition (default the project directory)")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if *descriptors == "" {
+		return errors.New("the -d flag is required")
+	}
+	if flags.NArg() > 1 {
+		return errors.New("lsp takes at most one project directory")
+	}
+	files, err := loadFiles(*descriptors)
+	if err != nil {
+		return err
+	}
+	types, err := newTypes(files)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *descriptors, err)
+	}
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	config, err := lint.LoadConfig(os.DirFS(root), *configPath)
+	if err != nil {
+		return err
+	}
+	server := lsp.Server{
+		Config:    config,
+		Root:      root,
+		ProtoRoot: *protoRoot,
+		Resolver:  types,
+	}
+	return server.Serve(context.Background(), stdin, stdout)
+}
+
+func getDiagnosticsWriter(format string) (func(protoyaml.ErrorList, io.Writer) error, error) {
+	switch format {
+	case "text":
+		return func(errs protoyaml.ErrorList, w io.Writer) error {
+			_, err := fmt.Fprintln(w, errs.Error())
+			return err
+		}, nil
+	case "json":
+		return protoyaml.ErrorList.WriteJSON, nil
+	case "sarif":
+		return protoyaml.ErrorList.WriteSARIF, nil
+	case "github":
+		return protoyaml.ErrorList.WriteGitHubAnnotations, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected one of text, json, sarif or github", format)
+	}
+}
+
+func runFormat(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var typeFlags typeFlags
+	typeFlags.register(flags)
+	write := flags.Bool("w", false, "write the formatted files in place, instead of to stdout")
+	check := flags.Bool("check", false, "list the files that are not formatted, and exit with a non-zero status if there are any")
+	indent := flags.Int("indent", 2, "the number of spaces to indent")
+	protoNames := flags.Bool("proto-names", false, "name fields by their proto names instead of their JSON names")
+	allowPartial := flags.Bool("allow-partial", false, "allow missing required fields")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	msgType, types, err := typeFlags.load()
+	if err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	formatter := &formatter{
+		msgType: msgType,
+		options: protoyaml.MarshalOptions{
+			Indent:        *indent,
+			UseProtoNames: *protoNames,
+			AllowPartial:  *allowPartial,
+			Resolver:      types,
+		},
+		write:  *write,
+		check:  *check,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	failed := false
+	for _, path := range paths {
+		ok, err := formatter.formatPath(path)
+		if err != nil {
+			return err
+		}
+		failed = failed || !ok
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// formatter formats files for the fmt command.
+type formatter struct {
+	msgType protoreflect.MessageType
+	options protoyaml.MarshalOptions
+	write   bool
+	check   bool
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// formatPath formats the given file, and returns false if it could not be
+// formatted, or is not formatted in check mode.
+func (f *formatter) formatPath(path string) (bool, error) {
+	if f.write && path == "-" {
+		return false, errors.New("cannot use -w with stdin")
+	}
+	data, formatted, err := formatFile(path, f.stdin, f.msgType, f.options)
+	switch {
+	case err != nil:
+		_, _ = fmt.Fprintln(f.stderr, err)
+		return false, nil
+	case f.check:
+		if bytes.Equal(data, formatted) {
+			return true, nil
+		}
+		_, err := fmt.Fprintln(f.stdout, path)
+		return false, err
+	case f.write:
+		if bytes.Equal(data, formatted) {
+			return true, nil
+		}
+		return true, os.WriteFile(path, formatted, 0o600)
+	default:
+		_, err := f.stdout.Write(formatted)
+		return true, err
+	}
+}
+
+// formatFile returns the content of the given file, and the content in
+// canonical form.
+func formatFile(path string, stdin io.Reader, msgType protoreflect.MessageType, options protoyaml.MarshalOptions) ([]byte, []byte, error) {
+	input, closeInput, err := openInput(path, stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeInput()
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	formatted, err := options.Format(data, msgType.New().Interface())
+	if err != nil {
+		if path == "-" {
+			path = "<stdin>"
+		}
+		var errs protoyaml.ErrorList
+		if !errors.As(err, &errs) {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, err := range errs {
+			err.Path = path
+		}
+		return nil, nil, errs
+	}
+	return data, formatted, nil
+}
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var typeFlags typeFlags
+	typeFlags.register(flags)
+	from := flags.String("from", "", "the `format` of the input: yaml, json, binpb or txtpb (default: from the file extension)")
+	to := flags.String("to", "", "the `format` of the output: yaml, json, binpb or txtpb (default: from the file extension)")
+	output := flags.String("o", "-", "the output `file`")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if flags.NArg() > 1 {
+		return errors.New("convert takes at most one input file")
+	}
+	path := flags.Arg(0)
+	if path == "" {
+		path = "-"
+	}
+	fromFormat, err := getFormat(*from, path)
+	if err != nil {
+		return err
+	}
+	toFormat, err := getFormat(*to, *output)
+	if err != nil {
+		return err
+	}
+	msgType, types, err := typeFlags.load()
+	if err != nil {
+		return err
+	}
+	input, closeInput, err := openInput(path, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	message := msgType.New().Interface()
+	if err := unmarshal(fromFormat, path, data, message, types); err != nil {
+		return err
+	}
+	data, err = marshal(toFormat, message, types)
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o600)
+}
+
+// parseError returns the error to return for the given error from parsing
+// flags, which has already been reported.
+func parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return errFailed
+}
+
+// getFormat returns the given format, or the format of the given file by its
+// extension if empty.
+func getFormat(format string, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			return formatYAML, nil
+		case ".json":
+			return formatJSON, nil
+		case ".binpb", ".pb", ".bin":
+			return formatBinary, nil
+		case ".txtpb", ".textproto", ".txt":
+			return formatText, nil
+		default:
+			if path == "-" {
+				return formatYAML, nil
+			}
+			return "", fmt.Errorf("unknown format of %s, use -from or -to", path)
+		}
+	}
+	switch format {
+	case formatYAML, formatJSON, formatBinary, formatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected one of yaml, json, binpb or txtpb", format)
+	}
+}
+
+func unmarshal(format string, path string, data []byte, message proto.Message, types *protoregistry.Types) error {
+	switch format {
+	case formatJSON:
+		return protojson.UnmarshalOptions{Resolver: types}.Unmarshal(data, message)
+	case formatBinary:
+		return proto.UnmarshalOptions{Resolver: types}.Unmarshal(data, message)
+	case formatText:
+		return prototext.UnmarshalOptions{Resolver: types}.Unmarshal(data, message)
+	default:
+		if path == "-" {
+			path = "<stdin>"
+		}
+		return protoyaml.UnmarshalOptions{Path: path, Resolver: types}.Unmarshal(data, message)
+	}
+}
+
+func marshal(format string, message proto.Message, types *protoregistry.Types) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		data, err := protojson.MarshalOptions{Multiline: true, Resolver: types}.Marshal(message)
+		return append(data, '\n'), err
+	case formatBinary:
+		return proto.Marshal(message)
+	case formatText:
+		return prototext.MarshalOptions{Multiline: true, Resolver: types}.Marshal(message)
+	default:
+		return protoyaml.MarshalOptions{Indent: 2, Resolver: types}.Marshal(message)
+	}
+}
+
+// openInput opens the given file, or returns stdin if the path is "-".
+func openInput(path string, stdin io.Reader) (io.Reader, func(), error) {
+	if path == "-" {
+		return stdin, func() {}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { _ = file.Close() }, nil
+}
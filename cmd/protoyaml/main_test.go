@@ -0,0 +1,227 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	descriptors := writeDescriptors(t, dir)
+	valid := writeFile(t, dir, "valid.yaml", "values:\n  - singleInt32: 1\n---\nvalues: []\n")
+	invalid := writeFile(t, dir, "invalid.yaml", "values:\n  - singleInt32: x\n---\nvalue: []\n")
+
+	stdout, _, err := runForTest(t, "", "validate", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test", valid)
+	require.NoError(t, err)
+	assert.Empty(t, stdout)
+
+	stdout, _, err = runForTest(t, "", "validate", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test", valid, invalid)
+	require.ErrorIs(t, err, errFailed)
+	assert.Contains(t, stdout, invalid+":2:18 invalid integer")
+	assert.Contains(t, stdout, invalid+":4:1 unknown field \"value\"")
+
+	stdout, _, err = runForTest(t, "values: [{singleInt32: x}]\n", "validate", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test", "-format", "json")
+	require.ErrorIs(t, err, errFailed)
+	assert.Contains(t, stdout, `"line":1,"column":24`)
+	assert.Contains(t, stdout, `"fieldPath":"values[0].single_int32"`)
+
+	// The types in the descriptors are suggested for unknown type URLs.
+	stdout, _, err = runForTest(t, "singleAny: {'@type': type.googleapis.com/buf.protoyaml.test.v1.Proto3Tset}\n", "validate", "-d", descriptors, "-t", "bufext.cel.expr.conformance.proto3.TestAllTypes")
+	require.ErrorIs(t, err, errFailed)
+	assert.Contains(t, stdout, `did you mean "type.googleapis.com/buf.protoyaml.test.v1.Proto3Test"?`)
+
+	_, _, err = runForTest(t, "", "validate", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Missing", valid)
+	require.ErrorContains(t, err, "message type buf.protoyaml.test.v1.Missing")
+	_, _, err = runForTest(t, "", "validate", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test", "-format", "xml", valid)
+	require.ErrorContains(t, err, `unknown format "xml"`)
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	descriptors := writeDescriptors(t, dir)
+	input := writeFile(t, dir, "input.yaml", "values:\n  - singleInt64: 1Ki\n    singleString: hello\n    singleAny:\n      \"@type\": type.googleapis.com/google.protobuf.Duration\n      value: 1s\n")
+	flags := []string{"convert", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test"}
+
+	// Convert through each format and back to YAML.
+	path := input
+	for _, name := range []string{"output.json", "output.binpb", "output.txtpb", "output.yaml"} {
+		output := filepath.Join(dir, name)
+		_, _, err := runForTest(t, "", append(flags, "-o", output, path)...)
+		require.NoError(t, err, name)
+		path = output
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `values:
+  - singleInt64: "1024"
+    singleString: hello
+    singleAny:
+      '@type': type.googleapis.com/google.protobuf.Duration
+      value: 1s
+`, string(data))
+
+	stdout, _, err := runForTest(t, "values: [{singleBool: true}]", append(flags, "-to", "json")...)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"values": [{"singleBool": true}]}`, stdout)
+
+	_, _, err = runForTest(t, "values: 1", append(flags, "-to", "json")...)
+	require.ErrorContains(t, err, "<stdin>:1:9 expected sequence, got scalar")
+	_, _, err = runForTest(t, "", append(flags, "-o", filepath.Join(dir, "output.xml"), input)...)
+	require.ErrorContains(t, err, "unknown format of")
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	descriptors := writeDescriptors(t, dir)
+	formatted := writeFile(t, dir, "formatted.yaml", "values:\n  - singleInt32: 1 # One.\n")
+	unformatted := writeFile(t, dir, "unformatted.yaml", "values:\n- single_int32: 0x1 # One.\n---\nvalues: [{single_int32: 2}]\n")
+	invalid := writeFile(t, dir, "invalid.yaml", "values: 1\n")
+	flags := []string{"fmt", "-d", descriptors, "-t", "buf.protoyaml.test.v1.Proto3Test"}
+
+	stdout, stderr, err := runForTest(t, "", append(flags, "-check", formatted, unformatted, invalid)...)
+	require.ErrorIs(t, err, errFailed)
+	assert.Equal(t, unformatted+"\n", stdout)
+	assert.Contains(t, stderr, invalid+":1:9 expected sequence, got scalar")
+
+	stdout, _, err = runForTest(t, "values: [{singleInt32: 1}]", flags...)
+	require.NoError(t, err)
+	assert.Equal(t, "values:\n  - singleInt32: 1\n", stdout)
+
+	_, _, err = runForTest(t, "", append(flags, "-w", unformatted)...)
+	require.NoError(t, err)
+	data, err := os.ReadFile(unformatted)
+	require.NoError(t, err)
+	assert.Equal(t, "values:\n  - singleInt32: 1 # One.\n---\nvalues:\n  - singleInt32: 2\n", string(data))
+	_, _, err = runForTest(t, "", append(flags, "-check", formatted, unformatted)...)
+	require.NoError(t, err)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	descriptors := writeDescriptors(t, t.TempDir())
+	writeFile(t, dir, "protoyaml-lint.yaml", "files:\n  - pattern: '**/*.yaml'\n    message: buf.protoyaml.test.v1.Proto3Test\nexclude: [protoyaml-lint.yaml]\n")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	writeFile(t, dir, "sub/b.yaml", "values: 1\n")
+	writeFile(t, dir, "a.yaml", "values: [{singleInt32: x}]\n")
+
+	stdout, _, err := runForTest(t, "", "lint", "-d", descriptors, dir)
+	require.ErrorIs(t, err, errFailed)
+	assert.Contains(t, stdout, "a.yaml:1:24 invalid integer")
+	assert.Less(t, strings.Index(stdout, "a.yaml:"), strings.Index(stdout, "sub/b.yaml:1:9 expected sequence"))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "sub/b.yaml")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.yaml")))
+	stdout, _, err = runForTest(t, "", "lint", "-d", descriptors, dir)
+	require.NoError(t, err)
+	assert.Empty(t, stdout)
+
+	_, _, err = runForTest(t, "", "lint", "-d", descriptors, "-config", "missing.yaml", dir)
+	require.ErrorContains(t, err, "missing.yaml")
+}
+
+func TestLSP(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	descriptors := writeDescriptors(t, t.TempDir())
+	writeFile(t, dir, "protoyaml-lint.yaml", "files:\n  - pattern: '*.yaml'\n    message: buf.protoyaml.test.v1.Proto3Test\n")
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "a.yaml"))}).String()
+	var stdin strings.Builder
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","version":1,"text":"values: [{singleInt32: x}]\n"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/completion","params":{"textDocument":{"uri":"` + uri + `"},"position":{"line":0,"character":19}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&stdin, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	stdout, _, err := runForTest(t, stdin.String(), "lsp", "-d", descriptors, dir)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, `"range":{"start":{"line":0,"character":23},"end":{"line":0,"character":24}},"severity":1,"source":"protoyaml","message":"invalid integer`)
+	assert.Contains(t, stdout, `"label":"singleInt32"`)
+
+	_, _, err = runForTest(t, "", "lsp", "-d", descriptors, "-config", "missing.yaml", dir)
+	require.ErrorContains(t, err, "missing.yaml")
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+	_, stderr, err := runForTest(t, "")
+	require.ErrorIs(t, err, errFailed)
+	assert.Contains(t, stderr, "Usage: protoyaml")
+	_, _, err = runForTest(t, "", "check")
+	require.ErrorContains(t, err, `unknown command "check"`)
+	_, stderr, err = runForTest(t, "", "convert", "-h")
+	require.NoError(t, err)
+	assert.Contains(t, stderr, "-from format")
+}
+
+func runForTest(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+// writeDescriptors writes a FileDescriptorSet of the test messages, excluding
+// the well-known types.
+func writeDescriptors(t *testing.T, dir string) string {
+	t.Helper()
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(file protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] || strings.HasPrefix(file.Path(), "google/protobuf/") {
+			return
+		}
+		seen[file.Path()] = true
+		for i := range file.Imports().Len() {
+			add(file.Imports().Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	add(testv1.File_buf_protoyaml_test_v1_pb3_proto)
+	add(proto3.File_bufext_cel_expr_conformance_proto3_test_all_types_proto)
+	data, err := proto.Marshal(set)
+	require.NoError(t, err)
+	return writeFile(t, dir, "image.binpb", string(data))
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
@@ -18,12 +18,14 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"buf.build/go/protovalidate"
 	"go.yaml.in/yaml/v3"
 	"google.golang.org/protobuf/proto"
@@ -50,6 +52,11 @@ type UnmarshalOptions struct {
 	//
 	// If set, this will be used when producing error messages.
 	Path string
+	// RootPath is the path of the node to unmarshal the message from, if the
+	// message is a section of a larger document, such as `spec.config` or
+	// `items[0].config`. Includes and aliases are followed. If not set, the
+	// message is unmarshaled from the whole document.
+	RootPath string
 	// Validator is a validator to run after unmarshaling a message.
 	Validator Validator
 	// CustomUnmarshaler is a custom unmarshaler to use for specific message types.
@@ -67,6 +74,55 @@ type UnmarshalOptions struct {
 	// DiscardUnknown specifies whether to discard unknown fields instead of
 	// returning an error.
 	DiscardUnknown bool
+
+	// IncludeFS is the file system used to resolve `!include` tags. If nil,
+	// `!include` tags are not resolved.
+	//
+	// Included paths are relative to the including file. Paths in the input
+	// itself are relative to the root of IncludeFS. Errors in included files
+	// are reported with paths relative to the directory of Path.
+	IncludeFS fs.FS
+
+	// LookupVariable enables the interpolation of `${NAME}` and
+	// `${NAME:-default}` references in scalar values, and returns the value of
+	// the named variable and whether it is set. For example, use os.LookupEnv
+	// to interpolate environment variables. If nil, values are not interpolated.
+	//
+	// The default is used if the variable is not set or is empty. Use `$$` for
+	// a literal `$`. Mapping keys are not interpolated.
+	LookupVariable func(name string) (string, bool)
+
+	// TagHandlers are the handlers for custom YAML tags, such as `!file`, by tag.
+	// The handler for a tag is called for each field value, list element and
+	// map value with the tag.
+	TagHandlers map[string]TagHandler
+
+	// SecretResolver resolves the references of `!secret` tags, which may be
+	// used for string and bytes values. If nil, `!secret` tags are not
+	// resolved. Secret values are redacted from errors.
+	SecretResolver SecretResolver
+	// SecretReferences, if not nil, records the references of the resolved
+	// secrets, so that MarshalOptions can emit them in place of the values.
+	SecretReferences *SecretReferences
+
+	// The following limits guard against untrusted input. A value of 0 means
+	// there is no limit, except for MaxAliasExpansion.
+
+	// MaxDepth is the maximum nesting depth of YAML nodes, with aliases expanded.
+	MaxDepth int
+	// MaxAliasExpansion is the maximum number of YAML nodes that may be
+	// produced by expanding aliases. If 0, DefaultMaxAliasExpansion is used, so
+	// that documents with nested aliases cannot exhaust memory. A negative
+	// value means there is no limit.
+	MaxAliasExpansion int
+	// MaxDocumentBytes is the maximum size of a YAML document, in bytes. It
+	// also limits the total size of the files included by the document, which
+	// is DefaultMaxIncludeBytes if 0.
+	MaxDocumentBytes int
+	// MaxNodes is the maximum number of YAML nodes in a document, with aliases
+	// expanded. It also limits the total number of nodes in the files included
+	// by the document.
+	MaxNodes int
 }
 
 // Validator is an interface for validating a Protobuf message produced from a given YAML node.
@@ -99,19 +155,28 @@ func Unmarshal(data []byte, message proto.Message) error {
 
 // Unmarshal a Protobuf message from the given YAML data.
 func (o UnmarshalOptions) Unmarshal(data []byte, message proto.Message) error {
-	var yamlFile yaml.Node
-	if err := yaml.Unmarshal(data, &yamlFile); err != nil {
-		return err
-	}
-	if err := o.unmarshalNode(&yamlFile, message, data); err != nil {
-		return err
+	if o.MaxDocumentBytes > 0 && len(data) > o.MaxDocumentBytes {
+		return o.newDocumentTooLargeError(data, 0)
 	}
-	if !o.AllowPartial {
-		if err := proto.CheckInitialized(message); err != nil {
-			return err
-		}
+	var yamlFile yaml.Node
+	if err := parseYAML(data, &yamlFile); err != nil {
+		return o.newSyntaxError(err, data, 0)
 	}
-	return nil
+	return o.unmarshalDocument(&yamlFile, message, data, 0)
+}
+
+// UnmarshalNode unmarshals a Protobuf message from the given YAML node.
+func UnmarshalNode(node *yaml.Node, message proto.Message) error {
+	return (UnmarshalOptions{}).UnmarshalNode(node, message)
+}
+
+// UnmarshalNode unmarshals a Protobuf message from the given YAML node, such as
+// a section of a larger YAML document.
+//
+// Errors are reported at the line and column of the nodes they refer to, but
+// without source snippets, since the source text is not known.
+func (o UnmarshalOptions) UnmarshalNode(node *yaml.Node, message proto.Message) error {
+	return o.unmarshalDocument(node, message, nil, 0)
 }
 
 // ParseDuration parses a duration string into a durationpb.Duration.
@@ -161,14 +226,36 @@ func ParseDuration(str string) (*durationpb.Duration, error) {
 	return result, nil
 }
 
-func (o UnmarshalOptions) unmarshalNode(node *yaml.Node, message proto.Message, data []byte) error {
-	if node.Kind == 0 {
-		return nil
+// unmarshalDocument unmarshals the given document node and checks that all
+// required fields are set.
+//
+// Missing required fields are reported along with the other errors as the
+// document is unmarshaled. The final check catches messages that were not
+// unmarshaled field by field, such as those handled by a CustomUnmarshaler.
+//
+// The data is the source of the document, which starts after lineOffset lines
+// of the original input.
+func (o UnmarshalOptions) unmarshalDocument(node *yaml.Node, message proto.Message, data []byte, lineOffset int) error {
+	if err := o.unmarshalNode(node, message, data, lineOffset); err != nil {
+		return err
+	}
+	if !o.AllowPartial {
+		if err := proto.CheckInitialized(message); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+func (o UnmarshalOptions) unmarshalNode(node *yaml.Node, message proto.Message, data []byte, lineOffset int) error {
 	unm := &unmarshaler{
-		options:   o,
-		validator: o.Validator,
-		lines:     strings.Split(string(data), "\n"),
+		options:    o,
+		validator:  o.Validator,
+		lines:      strings.Split(string(data), "\n"),
+		lineOffset: lineOffset,
+	}
+	if node.Kind == 0 {
+		return unm.checkEmpty(message)
 	}
 
 	// Unwrap the document node
@@ -178,6 +265,27 @@ func (o UnmarshalOptions) unmarshalNode(node *yaml.Node, message proto.Message,
 		}
 		node = node.Content[0]
 	}
+	if o.IncludeFS != nil {
+		node = unm.resolveIncludes(node, nil)
+		if len(unm.errors) > 0 {
+			return unm.errorList()
+		}
+	}
+	if !unm.checkLimits(node) {
+		return unm.errorList()
+	}
+	node = unm.resolveAliases(node)
+	if o.RootPath != "" {
+		if node = unm.findRoot(node); node == nil {
+			return unm.errorList()
+		}
+	}
+	if o.LookupVariable != nil {
+		node = unm.interpolate(node)
+		if len(unm.errors) > 0 {
+			return unm.errorList()
+		}
+	}
 
 	unm.unmarshalMessage(node, message, false)
 	if unm.validator != nil {
@@ -186,18 +294,55 @@ func (o UnmarshalOptions) unmarshalNode(node *yaml.Node, message proto.Message,
 		switch {
 		case err == nil: // Valid.
 		case errors.As(err, &verr):
+			msgDesc := message.ProtoReflect().Descriptor()
 			for _, violation := range verr.Violations {
-				closest := unm.nodeClosestToPath(node, message.ProtoReflect().Descriptor(), protovalidate.FieldPathString(violation.Proto.GetField()), violation.Proto.GetForKey())
-				unm.addError(closest, &violationError{
+				fieldPath := protovalidate.FieldPathString(violation.Proto.GetField())
+				closest := unm.nodeClosestToPath(node, msgDesc, fieldPath, violation.Proto.GetForKey())
+				violationErr := unm.newError(closest, &ViolationError{
 					Violation: violation.Proto,
 				})
+				violationErr.FieldPath = fieldPath
+				violationErr.Descriptor = findContainingMessage(msgDesc, violation.Proto.GetField().GetElements())
+				unm.errors = append(unm.errors, violationErr)
 			}
 		default:
 			unm.addError(node, err)
 		}
 	}
 
-	return errors.Join(unm.errors...)
+	return unm.errorList()
+}
+
+// checkEmpty checks an empty document for the given message, as a null
+// document. Errors are reported at the start of the document, as it has no
+// nodes.
+func (u *unmarshaler) checkEmpty(message proto.Message) error {
+	start := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: u.lineOffset + 1, Column: 1}
+	if u.options.RootPath != "" {
+		u.addErrorf(start, "root path %#v not found: the document is empty", u.options.RootPath)
+	} else {
+		u.checkRequired(start, message)
+	}
+	return u.errorList()
+}
+
+// findContainingMessage returns the descriptor of the message that contains the
+// last field in the given path.
+func findContainingMessage(msgDesc protoreflect.MessageDescriptor, elements []*validate.FieldPathElement) protoreflect.MessageDescriptor {
+	for _, element := range elements[:max(len(elements)-1, 0)] {
+		field := msgDesc.Fields().ByNumber(protoreflect.FieldNumber(element.GetFieldNumber()))
+		switch {
+		case field == nil:
+			return msgDesc
+		case field.IsMap():
+			field = field.MapValue()
+		}
+		if field.Message() == nil {
+			return msgDesc
+		}
+		msgDesc = field.Message()
+	}
+	return msgDesc
 }
 
 const atTypeFieldName = "@type"
@@ -209,23 +354,106 @@ type protoResolver interface {
 
 type unmarshaler struct {
 	options   UnmarshalOptions
-	errors    []error
+	errors    []*Error
 	validator Validator
 	lines     []string
+	// The number of lines in the input that precede lines[0].
+	lineOffset int
+	// The resolved content of each anchor.
+	anchors map[*yaml.Node]*yaml.Node
+	// The aliases each copied node was expanded from, innermost first.
+	aliasSites map[*yaml.Node][]*yaml.Node
+	// The file each node was included from, if not the input itself.
+	sourceFiles map[*yaml.Node]*sourceFile
+	// The anchors with includes resolved.
+	includedAnchors map[*yaml.Node]*yaml.Node
+	// The total size and number of nodes of the included files.
+	includedBytes, includedNodes int
+	// The path to the field being unmarshaled, as a list of segments.
+	fieldPath []string
+	// The message being unmarshaled.
+	message protoreflect.MessageDescriptor
+	// The values of the resolved secrets, to redact from errors.
+	secrets []string
 }
 
 func (u *unmarshaler) addError(node *yaml.Node, err error) {
-	u.errors = append(u.errors, &nodeError{
-		Path:  u.options.Path,
-		Node:  node,
-		cause: err,
-		line:  u.lines[node.Line-1],
-	})
+	u.errors = append(u.errors, u.newError(node, err))
 }
+
 func (u *unmarshaler) addErrorf(node *yaml.Node, format string, args ...any) {
 	u.addError(node, fmt.Errorf(format, args...))
 }
 
+// newError returns an Error for the given node, at the current field path.
+func (u *unmarshaler) newError(node *yaml.Node, err error) *Error {
+	site := u.newSourceSite(node)
+	result := newError(site.Path, node, site.line, err)
+	result.FieldPath = strings.Join(u.fieldPath, "")
+	result.Descriptor = u.message
+	for _, alias := range u.aliasSites[node] {
+		result.aliases = append(result.aliases, u.newSourceSite(alias))
+	}
+	result.includes = u.includeSites(u.sourceFiles[node])
+	return result
+}
+
+// newSourceSite returns the location of the given node, in the file that
+// contains it.
+func (u *unmarshaler) newSourceSite(node *yaml.Node) *sourceSite {
+	path := u.options.Path
+	if file := u.sourceFiles[node]; file != nil {
+		path = file.path
+	}
+	return &sourceSite{Path: path, Node: node, line: u.nodeSourceLine(node, node.Line)}
+}
+
+// nodeSourceLine returns the source text of the given 1-based line number, in
+// the file that contains the given node.
+func (u *unmarshaler) nodeSourceLine(node *yaml.Node, line int) string {
+	if file := u.sourceFiles[node]; file != nil {
+		return file.line(line)
+	}
+	return u.sourceLine(line)
+}
+
+// errorList returns the errors found so far, or nil if there are none.
+func (u *unmarshaler) errorList() error {
+	if len(u.errors) == 0 {
+		return nil
+	}
+	u.redactSecrets(u.errors)
+	return ErrorList(u.errors)
+}
+
+// sourceLine returns the source text of the given 1-based line number, or an
+// empty string if the line is not known.
+func (u *unmarshaler) sourceLine(line int) string {
+	index := line - 1 - u.lineOffset
+	if index < 0 || index >= len(u.lines) {
+		return ""
+	}
+	return u.lines[index]
+}
+
+// pushFieldName appends the given field name to the field path.
+func (u *unmarshaler) pushFieldName(name string) {
+	if len(u.fieldPath) > 0 {
+		name = "." + name
+	}
+	u.fieldPath = append(u.fieldPath, name)
+}
+
+// pushSubscript appends the given list index or map key to the field path.
+func (u *unmarshaler) pushSubscript(subscript string) {
+	u.fieldPath = append(u.fieldPath, "["+subscript+"]")
+}
+
+// popFieldPath removes the last segment of the field path.
+func (u *unmarshaler) popFieldPath() {
+	u.fieldPath = u.fieldPath[:len(u.fieldPath)-1]
+}
+
 func (u *unmarshaler) checkKind(node *yaml.Node, expected yaml.Kind) bool {
 	if node.Kind != expected {
 		u.addErrorf(node, "expected %v, got %v", getNodeKind(expected), getNodeKind(node.Kind))
@@ -257,6 +485,9 @@ func (u *unmarshaler) resolveAnyType(typeURL string) (protoreflect.MessageType,
 	// Get the message type.
 	msgType, err := u.getResolver().FindMessageByURL(typeURL)
 	if err != nil {
+		if suggestion := findTypeURLSuggestion(typeURL, u.getResolver()); suggestion != "" {
+			return nil, fmt.Errorf("unknown type %#v, did you mean %#v?", typeURL, suggestion)
+		}
 		return nil, err
 	}
 	return msgType, nil
@@ -365,8 +596,12 @@ func (u *unmarshaler) unmarshalEnum(node *yaml.Node, field protoreflect.FieldDes
 	if enumVal == nil {
 		lit, err := parseIntLiteral(node.Value, false)
 		if err != nil {
-			u.addErrorf(node, "unknown enum value %#v, expected one of %v", node.Value,
-				getEnumValueNames(enumDesc.Values()))
+			if suggestion := findEnumSuggestion(node.Value, enumDesc.Values()); suggestion != "" {
+				u.addErrorf(node, "unknown enum value %#v, did you mean %#v?", node.Value, suggestion)
+			} else {
+				u.addErrorf(node, "unknown enum value %#v, expected one of %v", node.Value,
+					getEnumValueNames(enumDesc.Values()))
+			}
 			return 0
 		} else if err := lit.checkI32(field); err != nil {
 			u.addErrorf(node, "%w, expected one of %v", err,
@@ -602,6 +837,11 @@ func (u *unmarshaler) findField(key string, msgDesc protoreflect.MessageDescript
 
 // Unmarshal a field, handling isList/isMap.
 func (u *unmarshaler) unmarshalField(node *yaml.Node, field protoreflect.FieldDescriptor, message proto.Message) {
+	u.pushFieldName(getFieldPathName(field))
+	defer u.popFieldPath()
+	if node = u.handleTag(node, field); node == nil {
+		return // Error already added.
+	}
 	if oneofDesc := field.ContainingOneof(); oneofDesc != nil && !oneofDesc.IsSynthetic() {
 		// Check if another field in the oneof is already set.
 		if whichOne := message.ProtoReflect().WhichOneof(oneofDesc); whichOne != nil {
@@ -632,18 +872,24 @@ func (u *unmarshaler) unmarshalList(node *yaml.Node, field protoreflect.FieldDes
 	if u.checkKind(node, yaml.SequenceNode) {
 		switch field.Kind() {
 		case protoreflect.MessageKind, protoreflect.GroupKind:
-			for _, itemNode := range node.Content {
-				msgVal := list.NewElement()
-				u.unmarshalMessage(itemNode, msgVal.Message().Interface(), false)
-				list.Append(msgVal)
+			for i, itemNode := range node.Content {
+				u.pushSubscript(strconv.Itoa(i))
+				if itemNode = u.handleTag(itemNode, field); itemNode != nil {
+					msgVal := list.NewElement()
+					u.unmarshalMessage(itemNode, msgVal.Message().Interface(), false)
+					list.Append(msgVal)
+				}
+				u.popFieldPath()
 			}
 		default:
-			for _, itemNode := range node.Content {
-				val, ok := u.unmarshalScalar(itemNode, field, false)
-				if !ok {
-					continue
+			for i, itemNode := range node.Content {
+				u.pushSubscript(strconv.Itoa(i))
+				if itemNode = u.handleTag(itemNode, field); itemNode != nil {
+					if val, ok := u.unmarshalScalar(itemNode, field, false); ok {
+						list.Append(val)
+					}
 				}
-				list.Append(val)
+				u.popFieldPath()
 			}
 		}
 	}
@@ -663,19 +909,37 @@ func (u *unmarshaler) unmarshalMap(node *yaml.Node, field protoreflect.FieldDesc
 		if !ok {
 			continue
 		}
-		switch mapValueField.Kind() {
-		case protoreflect.MessageKind, protoreflect.GroupKind:
+		u.pushSubscript(getMapKeySubscript(mapKey.MapKey()))
+		valueNode = u.handleTag(valueNode, mapValueField)
+		switch {
+		case valueNode == nil: // Error already added.
+		case mapValueField.Message() != nil:
 			mapValue := mapVal.NewValue()
 			u.unmarshalMessage(valueNode, mapValue.Message().Interface(), false)
 			mapVal.Set(mapKey.MapKey(), mapValue)
 		default:
-			val, ok := u.unmarshalScalar(valueNode, mapValueField, false)
-			if !ok {
-				continue
+			if val, ok := u.unmarshalScalar(valueNode, mapValueField, false); ok {
+				mapVal.Set(mapKey.MapKey(), val)
 			}
-			mapVal.Set(mapKey.MapKey(), val)
 		}
+		u.popFieldPath()
+	}
+}
+
+// getFieldPathName returns the name of the field as it appears in a field path.
+func getFieldPathName(field protoreflect.FieldDescriptor) string {
+	if field.IsExtension() {
+		return "[" + string(field.FullName()) + "]"
 	}
+	return string(field.Name())
+}
+
+// getMapKeySubscript returns the given map key as it appears in a field path.
+func getMapKeySubscript(key protoreflect.MapKey) string {
+	if str, ok := key.Interface().(string); ok {
+		return strconv.Quote(str)
+	}
+	return key.String()
 }
 
 func isNull(node *yaml.Node) bool {
@@ -730,6 +994,7 @@ func (u *unmarshaler) unmarshalMessage(node *yaml.Node, message proto.Message, f
 		}
 	}
 	if isNull(node) {
+		u.checkRequired(node, message)
 		return // Null is always allowed for messages
 	}
 	if node.Kind != yaml.MappingNode {
@@ -743,6 +1008,9 @@ func (u *unmarshaler) unmarshalMessage(node *yaml.Node, message proto.Message, f
 func (u *unmarshaler) unmarshalMessageFields(node *yaml.Node, message proto.Message, forAny bool) {
 	// Decode the fields
 	msgDesc := message.ProtoReflect().Descriptor()
+	parent := u.message
+	u.message = msgDesc
+	defer func() { u.message = parent }()
 	for i := 0; i < len(node.Content); i += 2 {
 		keyNode := node.Content[i]
 		var key string
@@ -768,7 +1036,12 @@ func (u *unmarshaler) unmarshalMessageFields(node *yaml.Node, message proto.Mess
 		field, err := u.findField(key, msgDesc)
 		switch {
 		case errors.Is(err, protoregistry.NotFound):
-			if !u.options.DiscardUnknown {
+			if u.options.DiscardUnknown {
+				break
+			}
+			if suggestion := findFieldSuggestion(key, msgDesc.Fields()); suggestion != "" {
+				u.addErrorf(keyNode, "unknown field %#v, did you mean %#v?", key, suggestion)
+			} else {
 				u.addErrorf(keyNode, "unknown field %#v, expected one of %v", key, getFieldNames(msgDesc.Fields()))
 			}
 		case err != nil:
@@ -778,6 +1051,28 @@ func (u *unmarshaler) unmarshalMessageFields(node *yaml.Node, message proto.Mess
 			u.unmarshalField(valueNode, field, message)
 		}
 	}
+	u.checkRequired(node, message)
+}
+
+// checkRequired reports an error for each required field that is not set in the
+// given message, positioned at the node the message was unmarshaled from.
+func (u *unmarshaler) checkRequired(node *yaml.Node, message proto.Message) {
+	if u.options.AllowPartial {
+		return
+	}
+	msg := message.ProtoReflect()
+	parent := u.message
+	u.message = msg.Descriptor()
+	defer func() { u.message = parent }()
+	fields := msg.Descriptor().Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		if field.Cardinality() == protoreflect.Required && !msg.Has(field) {
+			u.pushFieldName(getFieldPathName(field))
+			u.addErrorf(node, "required field %v not set", field.FullName())
+			u.popFieldPath()
+		}
+	}
 }
 
 type customUnmarshaler func(u *unmarshaler, node *yaml.Node, message proto.Message) bool
@@ -800,7 +1095,8 @@ func unmarshalAnyMsg(unm *unmarshaler, node *yaml.Node, message proto.Message) b
 
 	protoVal := msgType.New()
 	unm.unmarshalMessage(node, protoVal.Interface(), true)
-	if err = anyVal.MarshalFrom(protoVal.Interface()); err != nil {
+	// Missing required fields have already been reported.
+	if err = anypb.MarshalFrom(anyVal, protoVal.Interface(), proto.MarshalOptions{AllowPartial: true}); err != nil {
 		unm.addErrorf(node, "failed to marshal %v: %v", msgType.Descriptor().FullName(), err)
 	}
 
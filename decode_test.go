@@ -214,3 +214,33 @@ values:
 	require.NoError(t, err)
 	require.Equal(t, "hi", actual.GetValues()[0].GetOneofStringValue())
 }
+
+func TestUnmarshalNode(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`
+name: service
+config:
+  values:
+    - oneof_string_value: hi
+    - oneof_int32_value: not a number
+`)
+	var document yaml.Node
+	require.NoError(t, yaml.Unmarshal(data, &document))
+	config := document.Content[0].Content[3]
+
+	actual := &testv1.Proto2Test{}
+	err := UnmarshalNode(config, actual)
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 1)
+	assert.Equal(t, 6, errList[0].Line)
+	assert.Equal(t, 26, errList[0].Column)
+	assert.Equal(t, "values[1].oneof_int32_value", errList[0].FieldPath)
+	assert.Equal(t, "hi", actual.GetValues()[0].GetOneofStringValue())
+
+	// Document nodes are unwrapped.
+	err = UnmarshalOptions{DiscardUnknown: true}.UnmarshalNode(&document, &testv1.Proto2Test{})
+	require.NoError(t, err)
+	require.NoError(t, UnmarshalNode(&yaml.Node{}, &testv1.Proto2Test{}))
+}
@@ -0,0 +1,213 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The rule ID used for errors that are not validation violations.
+const defaultRuleID = "protoyaml"
+
+// WriteJSON writes each error in the list as a JSON object on its own line.
+func (l ErrorList) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, err := range l {
+		if err := encoder.Encode(jsonDiagnostic{
+			Path:      err.Path,
+			Line:      err.Line,
+			Column:    err.Column,
+			EndLine:   err.EndLine,
+			EndColumn: err.EndColumn,
+			FieldPath: err.FieldPath,
+			RuleID:    err.ruleID(),
+			Message:   err.Cause.Error(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSARIF writes the errors in the list as a SARIF 2.1.0 log.
+func (l ErrorList) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "protoyaml",
+			InformationURI: "https://github.com/bufbuild/protoyaml-go",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndexes := make(map[string]int)
+	for _, err := range l {
+		ruleID := err.ruleID()
+		ruleIndex, ok := ruleIndexes[ruleID]
+		if !ok {
+			ruleIndex = len(run.Tool.Driver.Rules)
+			ruleIndexes[ruleID] = ruleIndex
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: ruleID})
+		}
+		result := sarifResult{
+			RuleID:    ruleID,
+			RuleIndex: ruleIndex,
+			Level:     "error",
+			Message:   sarifMessage{Text: err.Cause.Error()},
+			Locations: []sarifLocation{{
+				PhysicalLocation: newSARIFPhysicalLocation(err.Path, err.Line, err.Column, err.EndLine, err.EndColumn),
+			}},
+		}
+		for _, alias := range err.aliases {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				PhysicalLocation: newSARIFPhysicalLocation(alias.Path, alias.Node.Line, alias.Node.Column, alias.Node.Line, alias.Node.Column),
+				Message:          &sarifMessage{Text: "in expansion of alias *" + alias.Node.Value},
+			})
+		}
+		for _, include := range err.includes {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				PhysicalLocation: newSARIFPhysicalLocation(include.Path, include.Node.Line, include.Node.Column, include.Node.Line, include.Node.Column),
+				Message:          &sarifMessage{Text: "in inclusion of " + include.Node.Value},
+			})
+		}
+		run.Results = append(run.Results, result)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// WriteGitHubAnnotations writes each error in the list as a GitHub Actions
+// `::error` workflow command, which annotates the file in pull requests.
+func (l ErrorList) WriteGitHubAnnotations(w io.Writer) error {
+	for _, err := range l {
+		if _, err := fmt.Fprintf(w, "::error file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+			escapeGitHubProperty(err.Path),
+			err.Line, err.Column, err.EndLine, err.EndColumn,
+			escapeGitHubProperty(err.ruleID()),
+			escapeGitHubData(err.Cause.Error()),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleID returns the ID of the validation rule that caused the error, or a
+// generic ID for other errors.
+func (e *Error) ruleID() string {
+	var violationErr *ViolationError
+	if errors.As(e.Cause, &violationErr) && violationErr.Violation.GetRuleId() != "" {
+		return violationErr.Violation.GetRuleId()
+	}
+	return defaultRuleID
+}
+
+func escapeGitHubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
+
+type jsonDiagnostic struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	FieldPath string `json:"fieldPath,omitempty"`
+	RuleID    string `json:"ruleId"`
+	Message   string `json:"message"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func newSARIFPhysicalLocation(path string, line, column, endLine, endColumn int) sarifPhysicalLocation {
+	return sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: path},
+		Region: sarifRegion{
+			StartLine:   line,
+			StartColumn: column,
+			EndLine:     endLine,
+			EndColumn:   endColumn,
+		},
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"buf.build/go/protovalidate"
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiagnostics(t *testing.T) ErrorList {
+	t.Helper()
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+	options := UnmarshalOptions{
+		Path:      "dir/test,1.yaml",
+		Validator: &testValidator{validator: validator},
+	}
+	data := []byte(`cases:
+  - float_gt_lt: hi
+  - &case {float_gt_lt: 10.5}
+  - *case
+`)
+	err = options.Unmarshal(data, &testv1.ValidateTest{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 4)
+	return errList
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+	var buffer strings.Builder
+	require.NoError(t, testDiagnostics(t).WriteJSON(&buffer))
+	assert.Equal(t, `{"path":"dir/test,1.yaml","line":2,"column":18,"endLine":2,"endColumn":20,"fieldPath":"cases[0].float_gt_lt","ruleId":"protoyaml","message":"invalid float: strconv.ParseFloat: parsing \"hi\": invalid syntax"}
+{"path":"dir/test,1.yaml","line":2,"column":18,"endLine":2,"endColumn":20,"fieldPath":"cases[0].float_gt_lt","ruleId":"float.gt_lt","message":"cases[0].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)"}
+{"path":"dir/test,1.yaml","line":3,"column":25,"endLine":3,"endColumn":29,"fieldPath":"cases[1].float_gt_lt","ruleId":"float.gt_lt","message":"cases[1].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)"}
+{"path":"dir/test,1.yaml","line":3,"column":25,"endLine":3,"endColumn":29,"fieldPath":"cases[2].float_gt_lt","ruleId":"float.gt_lt","message":"cases[2].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)"}
+`, buffer.String())
+}
+
+func TestWriteSARIF(t *testing.T) {
+	t.Parallel()
+	var buffer strings.Builder
+	require.NoError(t, testDiagnostics(t).WriteSARIF(&buffer))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal([]byte(buffer.String()), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, []sarifRule{{ID: "protoyaml"}, {ID: "float.gt_lt"}}, run.Tool.Driver.Rules)
+	require.Len(t, run.Results, 4)
+	assert.Equal(t, "float.gt_lt", run.Results[3].RuleID)
+	assert.Equal(t, 1, run.Results[3].RuleIndex)
+	assert.Equal(t, sarifRegion{StartLine: 3, StartColumn: 25, EndLine: 3, EndColumn: 29}, run.Results[3].Locations[0].PhysicalLocation.Region)
+	require.Len(t, run.Results[3].RelatedLocations, 1)
+	assert.Equal(t, 4, run.Results[3].RelatedLocations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "in expansion of alias *case", run.Results[3].RelatedLocations[0].Message.Text)
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	t.Parallel()
+	var buffer strings.Builder
+	require.NoError(t, testDiagnostics(t).WriteGitHubAnnotations(&buffer))
+	assert.Equal(t, `::error file=dir/test%2C1.yaml,line=2,col=18,endLine=2,endColumn=20,title=protoyaml::invalid float: strconv.ParseFloat: parsing "hi": invalid syntax
+::error file=dir/test%2C1.yaml,line=2,col=18,endLine=2,endColumn=20,title=float.gt_lt::cases[0].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)
+::error file=dir/test%2C1.yaml,line=3,col=25,endLine=3,endColumn=29,title=float.gt_lt::cases[1].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)
+::error file=dir/test%2C1.yaml,line=3,col=25,endLine=3,endColumn=29,title=float.gt_lt::cases[2].float_gt_lt: value must be greater than 0 and less than 10 (float.gt_lt)
+`, buffer.String())
+}
@@ -0,0 +1,779 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Edit returns the given YAML data, edited to represent the given message.
+func Edit(data []byte, message proto.Message) ([]byte, error) {
+	return MarshalOptions{}.Edit(data, message)
+}
+
+// Edit returns the given YAML data, edited to represent the given message with
+// as few changes as possible, such as to update a configuration file that is
+// maintained by hand.
+//
+// The data must unmarshal to a message of the same type, using the Resolver
+// of the options. Changed scalar values are rewritten in place, new fields are
+// inserted in declaration order, and removed fields are deleted. Comments,
+// key order, and quoting are kept, except in values that cannot be edited in
+// place, such as flow collections and aliases, which are rewritten as a whole.
+// New keys use the same naming style as the existing keys.
+//
+// If SecretReferences is set, `!secret` references in the data resolve to the
+// recorded values, so that the secrets that did not change are kept as
+// references, and new values that match a recorded secret are written as its
+// reference. If Redact is set, the values of redacted fields are written as a
+// placeholder, and placeholders already in the data are kept.
+//
+// If the edited data would not unmarshal to the given message, such as when a
+// value that changed is shared through an anchor, the whole message is
+// marshaled instead.
+func (o MarshalOptions) Edit(data []byte, message proto.Message) ([]byte, error) {
+	var document yaml.Node
+	if err := parseYAML(data, &document); err != nil {
+		return nil, UnmarshalOptions{}.newSyntaxError(err, data, 0)
+	}
+	if message == nil || len(document.Content) != 1 {
+		return o.Marshal(message)
+	}
+	if !o.AllowPartial {
+		if err := proto.CheckInitialized(message); err != nil {
+			return nil, err
+		}
+	}
+	unmarshalOptions := UnmarshalOptions{Resolver: o.Resolver, AllowPartial: true}
+	if o.SecretReferences != nil {
+		unmarshalOptions.SecretResolver = referenceResolver{references: o.SecretReferences}
+	}
+	original := message.ProtoReflect().New()
+	if err := unmarshalOptions.Unmarshal(data, original.Interface()); err != nil {
+		return nil, err
+	}
+
+	root := document.Content[0]
+	editor := newEditor(o, data, root)
+	edited := editor.editMessage(root, original, message.ProtoReflect(), editor.documentEnd(root), o.UseProtoNames)
+	if editor.err != nil {
+		return nil, editor.err
+	}
+	if !edited {
+		return o.Marshal(message)
+	}
+	result := editor.apply()
+
+	check := message.ProtoReflect().New()
+	if err := unmarshalOptions.Unmarshal(result, check.Interface()); err != nil || !o.equalOutput(check.Interface(), message) {
+		return o.Marshal(message)
+	}
+	return result, nil
+}
+
+// equalOutput returns true if the given messages are equal, or, if Redact is
+// set, if they marshal to the same output.
+func (o MarshalOptions) equalOutput(x, y proto.Message) bool {
+	if !o.Redact {
+		return proto.Equal(x, y)
+	}
+	xData, err := o.Marshal(x)
+	if err != nil {
+		return false
+	}
+	yData, err := o.Marshal(y)
+	return err == nil && bytes.Equal(xData, yData)
+}
+
+// editor edits YAML source text to match a new message.
+type editor struct {
+	options MarshalOptions
+	unm     *unmarshaler
+	data    []byte
+	lines   []string
+	// The byte offset of the start of each line.
+	lineStarts []int
+	// The number of spaces to indent new nested values.
+	indent int
+	// The line ending of the data, used for all new lines.
+	newline string
+	edits   []textEdit
+	// The path of the field being edited, as recorded by SecretReferences.
+	fieldPath []string
+	// The first error, if any.
+	err error
+}
+
+// textEdit replaces the bytes in [start, end) with the text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// editEntry is an entry of a block mapping, or an item of a block sequence.
+type editEntry struct {
+	// The key of the mapping entry, or nil for a sequence item.
+	key   *yaml.Node
+	value *yaml.Node
+	// The field of a message entry, if known.
+	field protoreflect.FieldDescriptor
+	// The position of the key, or of the dash of the sequence item.
+	line, column int
+	// Whether the entry starts after other content on its line, such as the dash
+	// of a sequence item.
+	inline bool
+	// The 1-based lines of the entry, as [start, end). Include head comments,
+	// but not trailing blank lines and comments.
+	start, end int
+}
+
+func newEditor(options MarshalOptions, data []byte, root *yaml.Node) *editor {
+	e := &editor{
+		options: options,
+		unm:     &unmarshaler{options: UnmarshalOptions{Resolver: options.Resolver}},
+		data:    data,
+		lines:   strings.Split(string(data), "\n"),
+		indent:  options.Indent,
+		newline: "\n",
+	}
+	if index := bytes.IndexByte(data, '\n'); index > 0 && data[index-1] == '\r' {
+		e.newline = "\r\n"
+	}
+	offset := 0
+	for _, line := range e.lines {
+		e.lineStarts = append(e.lineStarts, offset)
+		offset += len(line) + 1
+	}
+	if e.indent <= 0 {
+		e.indent = detectIndent(root)
+	}
+	return e
+}
+
+// detectIndent returns the indentation of the first nested block mapping in
+// the given node, or 2 if there is none.
+func detectIndent(node *yaml.Node) int {
+	if node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && value.Line > key.Line && value.Column > key.Column {
+				return value.Column - key.Column
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if indent := detectIndent(child); indent != 2 {
+			return indent
+		}
+	}
+	return 2
+}
+
+// apply returns the data with all edits applied.
+func (e *editor) apply() []byte {
+	sort.SliceStable(e.edits, func(i, j int) bool {
+		if e.edits[i].start != e.edits[j].start {
+			return e.edits[i].start < e.edits[j].start
+		}
+		return e.edits[i].end < e.edits[j].end
+	})
+	var result bytes.Buffer
+	offset := 0
+	for _, edit := range e.edits {
+		result.Write(e.data[offset:edit.start])
+		result.WriteString(edit.text)
+		offset = edit.end
+	}
+	result.Write(e.data[offset:])
+	return result.Bytes()
+}
+
+// editMessage edits the given node from the before message to the after
+// message. The end is the line after the last line that may belong to the node.
+//
+// Returns false if the node cannot be edited in place.
+func (e *editor) editMessage(node *yaml.Node, before, after protoreflect.Message, end int, useProtoNames bool) bool {
+	if proto.Equal(before.Interface(), after.Interface()) {
+		return true
+	}
+	if findWKTMarshaler(after.Descriptor().FullName()) != nil || !isBlockNode(node, yaml.MappingNode) {
+		return false
+	}
+	entries := e.mappingEntries(node, end)
+	fields, byName := e.findEntryFields(entries, before, after)
+	useProtoNames = detectProtoNames(entries, useProtoNames)
+	for _, field := range fields {
+		e.pushFieldName(getFieldPathName(field))
+		ok := e.editField(entries, byName[field.FullName()], field, before, after, useProtoNames)
+		e.popFieldPath()
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// findEntryFields sets the fields of the given message entries, and returns
+// the fields that are set in the entries or in either message, with the entry
+// of each field.
+func (e *editor) findEntryFields(entries []*editEntry, before, after protoreflect.Message) ([]protoreflect.FieldDescriptor, map[protoreflect.FullName]*editEntry) {
+	byName := make(map[protoreflect.FullName]*editEntry, len(entries))
+	var fields []protoreflect.FieldDescriptor
+	addField := func(field protoreflect.FieldDescriptor) bool {
+		if _, ok := byName[field.FullName()]; !ok {
+			byName[field.FullName()] = nil
+			fields = append(fields, field)
+		}
+		return true
+	}
+	for _, entry := range entries {
+		field, err := e.unm.findField(entry.key.Value, after.Descriptor())
+		if err != nil {
+			continue // Such as a merge key.
+		}
+		entry.field = field
+		addField(field)
+		byName[field.FullName()] = entry
+	}
+	before.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		return addField(field)
+	})
+	after.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		return addField(field)
+	})
+	return fields, byName
+}
+
+// editField edits the given entry of the given field, if any, from its value
+// in the before message to its value in the after message.
+//
+// Returns false if the field cannot be edited in place.
+func (e *editor) editField(entries []*editEntry, entry *editEntry, field protoreflect.FieldDescriptor, before, after protoreflect.Message, useProtoNames bool) bool {
+	beforeHas, afterHas := before.Has(field), after.Has(field)
+	beforeVal, afterVal := before.Get(field), after.Get(field)
+	switch {
+	case !beforeHas && !afterHas:
+	case afterHas && entry != nil && e.options.Redact && e.options.isRedacted(field):
+		e.redactEntry(entry, field, afterVal, useProtoNames)
+	case beforeHas && afterHas && beforeVal.Equal(afterVal):
+	case !afterHas:
+		if entry == nil {
+			return false // Such as a value from a merge key.
+		}
+		e.deleteEntry(entry)
+	case entry == nil:
+		e.insertField(entries, field, afterVal, useProtoNames)
+	case !e.editValue(entry.value, field, beforeVal, afterVal, entry.end, useProtoNames):
+		e.replaceEntry(entry, e.renderField(field, afterVal, useProtoNames))
+	}
+	return true
+}
+
+// redactEntry replaces the value of the given entry of a redacted field with a
+// placeholder, unless it already is one.
+func (e *editor) redactEntry(entry *editEntry, field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) {
+	if entry.value.Kind == yaml.ScalarNode && entry.value.Value == redactedText {
+		return
+	}
+	placeholder := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redactedText}
+	if !e.replaceScalar(entry.value, placeholder, field) {
+		e.replaceEntry(entry, e.renderField(field, value, useProtoNames))
+	}
+}
+
+// editValue edits the given node from the before value to the after value of
+// the given field. Returns false if the node cannot be edited in place.
+func (e *editor) editValue(node *yaml.Node, field protoreflect.FieldDescriptor, before, after protoreflect.Value, end int, useProtoNames bool) bool {
+	switch {
+	case field.IsList():
+		return e.editList(node, field, before.List(), after.List(), end, useProtoNames)
+	case field.IsMap():
+		return e.editMap(node, field, before.Map(), after.Map(), end, useProtoNames)
+	default:
+		return e.editSingular(node, field, before, after, end, useProtoNames)
+	}
+}
+
+func (e *editor) editSingular(node *yaml.Node, field protoreflect.FieldDescriptor, before, after protoreflect.Value, end int, useProtoNames bool) bool {
+	if field.Message() != nil && findWKTMarshaler(field.Message().FullName()) == nil {
+		return e.editMessage(node, before.Message(), after.Message(), end, useProtoNames)
+	}
+	marshaler := e.marshaler(useProtoNames)
+	newNode, err := marshaler.marshalSingular(after, field)
+	if err != nil {
+		e.setError(err)
+		return true
+	}
+	if marshaler.options.SecretReferences != nil {
+		marshaler.options.replaceFieldSecrets(newNode, field, e.path())
+	}
+	return e.replaceScalar(node, newNode, field)
+}
+
+// editList edits the items of the given block sequence. Items are edited in
+// place, and items are appended or removed at the end.
+func (e *editor) editList(node *yaml.Node, field protoreflect.FieldDescriptor, before, after protoreflect.List, end int, useProtoNames bool) bool {
+	if !isBlockNode(node, yaml.SequenceNode) || len(node.Content) != before.Len() {
+		return false
+	}
+	items := e.sequenceEntries(node, end)
+	if items == nil {
+		return false
+	}
+	common := min(before.Len(), after.Len())
+	for i := range common {
+		beforeItem, afterItem := before.Get(i), after.Get(i)
+		if beforeItem.Equal(afterItem) {
+			continue
+		}
+		e.pushSubscript(strconv.Itoa(i))
+		if !e.editSingular(items[i].value, field, beforeItem, afterItem, items[i].end, useProtoNames) {
+			e.replaceEntry(items[i], e.renderItem(field, afterItem, useProtoNames))
+		}
+		e.popFieldPath()
+	}
+	for i := common; i < after.Len(); i++ {
+		e.pushSubscript(strconv.Itoa(i))
+		e.insertAfter(items[len(items)-1], e.renderItem(field, after.Get(i), useProtoNames))
+		e.popFieldPath()
+	}
+	for i := common; i < before.Len(); i++ {
+		e.deleteEntry(items[i])
+	}
+	return true
+}
+
+// editMap edits the entries of the given block mapping. New entries are
+// appended at the end.
+func (e *editor) editMap(node *yaml.Node, field protoreflect.FieldDescriptor, before, after protoreflect.Map, end int, useProtoNames bool) bool {
+	if !isBlockNode(node, yaml.MappingNode) {
+		return false
+	}
+	entries := e.mappingEntries(node, end)
+	byKey := make(map[string]*editEntry, len(entries))
+	for _, entry := range entries {
+		byKey[entry.key.Value] = entry
+	}
+	canEdit := true
+	before.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		if after.Has(key) {
+			return true
+		}
+		entry, ok := byKey[key.String()]
+		if !ok {
+			canEdit = false
+			return false
+		}
+		e.deleteEntry(entry)
+		return true
+	})
+	if !canEdit {
+		return false
+	}
+	keys := make([]protoreflect.MapKey, 0, after.Len())
+	after.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sortMapKeys(keys, field.MapKey().Kind())
+	valueField := field.MapValue()
+	for _, key := range keys {
+		afterVal := after.Get(key)
+		entry, ok := byKey[key.String()]
+		e.pushSubscript(getMapKeySubscript(key))
+		switch {
+		case !ok:
+			e.insertAfter(entries[len(entries)-1], e.renderMapEntry(key, valueField, afterVal, useProtoNames))
+		case before.Has(key) && before.Get(key).Equal(afterVal):
+		case !e.editSingular(entry.value, valueField, before.Get(key), afterVal, entry.end, useProtoNames):
+			e.replaceEntry(entry, e.renderMapEntry(key, valueField, afterVal, useProtoNames))
+		}
+		e.popFieldPath()
+	}
+	return true
+}
+
+// replaceScalar replaces the text of the given single-line scalar with the new
+// node, keeping its quoting. Returns false if the scalar cannot be replaced in
+// place.
+func (e *editor) replaceScalar(node *yaml.Node, newNode *yaml.Node, field protoreflect.FieldDescriptor) bool {
+	if node.Kind != yaml.ScalarNode || newNode.Kind != yaml.ScalarNode ||
+		node.Style&(yaml.TaggedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return false
+	}
+	endLine, endColumn := findNodeEnd(node, e.line(node.Line))
+	if endLine != node.Line || endColumn <= node.Column {
+		return false
+	}
+	start, end := e.offset(node.Line, node.Column), e.offset(node.Line, endColumn)
+	quoted := node.Style & (yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle)
+	if quoted == 0 && string(e.data[start:end]) != node.Value {
+		return false // A plain scalar that spans multiple lines.
+	}
+	replacement := *newNode
+	if replacement.Tag == "!!str" {
+		switch {
+		case quoted != 0:
+			replacement.Style = quoted
+		case isInt64Kind(field.Kind()) && node.ShortTag() == "!!int":
+			// Keep 64-bit integers that were written as numbers unquoted.
+			plain := &yaml.Node{Kind: yaml.ScalarNode, Value: replacement.Value}
+			replacement.Tag = plain.ShortTag()
+		}
+	}
+	text, err := yaml.Marshal(&replacement)
+	if err != nil {
+		e.setError(err)
+		return true
+	}
+	value := strings.TrimSuffix(string(text), "\n")
+	if strings.Contains(value, "\n") {
+		return false
+	}
+	e.edits = append(e.edits, textEdit{start: start, end: end, text: value})
+	return true
+}
+
+// insertField inserts an entry for the given field among the given entries,
+// after the last entry for a field that is declared before it, or before the
+// first entry if there is none.
+func (e *editor) insertField(entries []*editEntry, field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) {
+	lines := e.renderField(field, value, useProtoNames)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].field != nil && fieldLess(entries[i].field, field) {
+			e.insertAfter(entries[i], lines)
+			return
+		}
+	}
+	e.insertBefore(entries[0], lines)
+}
+
+func (e *editor) insertBefore(entry *editEntry, lines []string) {
+	if entry.inline {
+		start := e.offset(entry.line, entry.column)
+		text := e.indentLines(lines, entry.column, false) + strings.Repeat(" ", entry.column-1)
+		e.edits = append(e.edits, textEdit{start: start, end: start, text: text})
+		return
+	}
+	start := e.lineOffset(entry.start)
+	e.edits = append(e.edits, textEdit{start: start, end: start, text: e.indentLines(lines, entry.column, true)})
+}
+
+func (e *editor) insertAfter(entry *editEntry, lines []string) {
+	start := e.lineOffset(entry.end)
+	text := e.indentLines(lines, entry.column, true)
+	if start == len(e.data) && len(e.data) > 0 && e.data[len(e.data)-1] != '\n' {
+		text = e.newline + text
+	}
+	e.edits = append(e.edits, textEdit{start: start, end: start, text: text})
+}
+
+// replaceEntry replaces the given entry with the given lines, keeping its head
+// comments.
+func (e *editor) replaceEntry(entry *editEntry, lines []string) {
+	text := e.indentLines(lines, entry.column, false)
+	end := e.lineOffset(entry.end)
+	if end == len(e.data) && (len(e.data) == 0 || e.data[len(e.data)-1] != '\n') {
+		text = strings.TrimSuffix(text, e.newline)
+	}
+	e.edits = append(e.edits, textEdit{start: e.offset(entry.line, entry.column), end: end, text: text})
+}
+
+// deleteEntry deletes the given entry and its head comments.
+func (e *editor) deleteEntry(entry *editEntry) {
+	end := e.lineOffset(entry.end)
+	if !entry.inline {
+		e.edits = append(e.edits, textEdit{start: e.lineOffset(entry.start), end: end})
+		return
+	}
+	// Keep the content before the entry, such as the dash of a sequence item.
+	start := e.offset(entry.line, entry.column)
+	for start > 0 && (e.data[start-1] == ' ' || e.data[start-1] == '\t') {
+		start--
+	}
+	e.edits = append(e.edits, textEdit{start: start, end: end, text: e.newline})
+}
+
+// mappingEntries returns the entries of the given block mapping.
+func (e *editor) mappingEntries(node *yaml.Node, end int) []*editEntry {
+	entries := make([]*editEntry, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		entries = append(entries, e.newEntry(key, node.Content[i+1], key.Line, key.Column))
+	}
+	e.setEntryEnds(entries, end)
+	return entries
+}
+
+// sequenceEntries returns the items of the given block sequence, or nil if
+// the dash of an item cannot be found.
+func (e *editor) sequenceEntries(node *yaml.Node, end int) []*editEntry {
+	entries := make([]*editEntry, 0, len(node.Content))
+	for _, item := range node.Content {
+		offset := e.offset(item.Line, item.Column)
+		for offset > 0 && strings.ContainsRune(" \t\r\n", rune(e.data[offset-1])) {
+			offset--
+		}
+		if offset == 0 || e.data[offset-1] != '-' {
+			return nil
+		}
+		line, column := e.position(offset - 1)
+		entries = append(entries, e.newEntry(nil, item, line, column))
+	}
+	e.setEntryEnds(entries, end)
+	return entries
+}
+
+func (e *editor) newEntry(key *yaml.Node, value *yaml.Node, line, column int) *editEntry {
+	entry := &editEntry{key: key, value: value, line: line, column: column, start: line}
+	prefix := e.line(line)[:e.offset(line, column)-e.lineOffset(line)]
+	if strings.TrimSpace(prefix) != "" {
+		entry.inline = true
+		return entry
+	}
+	// Include the comment lines just above the entry, at the same indentation.
+	for entry.start > 1 {
+		text := e.line(entry.start - 1)
+		trimmed := strings.TrimLeft(text, " \t")
+		if !strings.HasPrefix(trimmed, "#") || len(text)-len(trimmed) != column-1 {
+			break
+		}
+		entry.start--
+	}
+	return entry
+}
+
+// setEntryEnds sets the end of each entry to the start of the next entry, or
+// to the given end for the last entry, without trailing blank lines and
+// comments that are not indented more than the entry.
+func (e *editor) setEntryEnds(entries []*editEntry, end int) {
+	for i, entry := range entries {
+		entryEnd := end
+		if i+1 < len(entries) {
+			entryEnd = entries[i+1].start
+		}
+		for entryEnd > entry.line+1 {
+			text := e.line(entryEnd - 1)
+			trimmed := strings.TrimLeft(text, " \t")
+			isComment := strings.HasPrefix(trimmed, "#") && len(text)-len(trimmed) <= entry.column-1
+			if strings.TrimSpace(trimmed) != "" && !isComment {
+				break
+			}
+			entryEnd--
+		}
+		entry.end = max(entryEnd, entry.line+1)
+	}
+}
+
+// documentEnd returns the line after the last line of the document that
+// contains the given root node.
+func (e *editor) documentEnd(root *yaml.Node) int {
+	for i := root.Line; i < len(e.lines); i++ {
+		if strings.HasPrefix(e.lines[i], "---") || strings.HasPrefix(e.lines[i], "...") {
+			return i + 1
+		}
+	}
+	return len(e.lines) + 1
+}
+
+func (e *editor) renderField(field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) []string {
+	name := field.JSONName()
+	if useProtoNames {
+		name = field.TextName()
+	}
+	marshaler := e.marshaler(useProtoNames)
+	options := marshaler.options
+	valueNode, err := marshaler.marshalValue(value, field)
+	if err != nil {
+		e.setError(err)
+		return nil
+	}
+	if options.SecretReferences != nil {
+		options.replaceValueSecrets(valueNode, field, e.path())
+	}
+	if options.Redact {
+		valueNode = options.redactValue(valueNode, field)
+	}
+	return e.render(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{newStringNode(name), valueNode}})
+}
+
+func (e *editor) renderMapEntry(key protoreflect.MapKey, field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) []string {
+	valueNode := e.renderSingular(field, value, useProtoNames)
+	if valueNode == nil {
+		return nil
+	}
+	return e.render(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{newStringNode(key.String()), valueNode}})
+}
+
+func (e *editor) renderItem(field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) []string {
+	valueNode := e.renderSingular(field, value, useProtoNames)
+	if valueNode == nil {
+		return nil
+	}
+	return e.render(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{valueNode}})
+}
+
+// renderSingular returns the node of the given list item or map value, with
+// secrets replaced and fields redacted as the options require.
+func (e *editor) renderSingular(field protoreflect.FieldDescriptor, value protoreflect.Value, useProtoNames bool) *yaml.Node {
+	marshaler := e.marshaler(useProtoNames)
+	valueNode, err := marshaler.marshalSingular(value, field)
+	if err != nil {
+		e.setError(err)
+		return nil
+	}
+	if marshaler.options.SecretReferences != nil {
+		marshaler.options.replaceFieldSecrets(valueNode, field, e.path())
+	}
+	if msgDesc := field.Message(); msgDesc != nil && marshaler.options.Redact {
+		marshaler.options.redactFields(valueNode, msgDesc)
+	}
+	return valueNode
+}
+
+// render returns the lines of the given node, encoded as YAML.
+func (e *editor) render(node *yaml.Node) []string {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(e.indent)
+	if err := encoder.Encode(node); err != nil {
+		e.setError(err)
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+}
+
+func (e *editor) marshaler(useProtoNames bool) *marshaler {
+	options := e.options
+	options.UseProtoNames = useProtoNames
+	return &marshaler{options: options, resolver: options.getResolver()}
+}
+
+func (e *editor) pushFieldName(name string) {
+	if len(e.fieldPath) > 0 {
+		name = "." + name
+	}
+	e.fieldPath = append(e.fieldPath, name)
+}
+
+func (e *editor) pushSubscript(subscript string) {
+	e.fieldPath = append(e.fieldPath, "["+subscript+"]")
+}
+
+func (e *editor) popFieldPath() {
+	e.fieldPath = e.fieldPath[:len(e.fieldPath)-1]
+}
+
+func (e *editor) path() string {
+	return strings.Join(e.fieldPath, "")
+}
+
+func (e *editor) setError(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// line returns the text of the given 1-based line, or an empty string if there
+// is no such line.
+func (e *editor) line(line int) string {
+	if line < 1 || line > len(e.lines) {
+		return ""
+	}
+	return e.lines[line-1]
+}
+
+// lineOffset returns the byte offset of the start of the given 1-based line,
+// or the length of the data if there is no such line.
+func (e *editor) lineOffset(line int) int {
+	if line > len(e.lines) {
+		return len(e.data)
+	}
+	return e.lineStarts[line-1]
+}
+
+// offset returns the byte offset of the given 1-based line and column.
+func (e *editor) offset(line, column int) int {
+	text := e.line(line)
+	index := 0
+	for range column - 1 {
+		_, size := utf8.DecodeRuneInString(text[index:])
+		if size == 0 {
+			break
+		}
+		index += size
+	}
+	return e.lineOffset(line) + index
+}
+
+// position returns the 1-based line and column of the given byte offset.
+func (e *editor) position(offset int) (int, int) {
+	line := sort.Search(len(e.lineStarts), func(i int) bool {
+		return e.lineStarts[i] > offset
+	})
+	start := e.lineStarts[line-1]
+	return line, utf8.RuneCount(e.data[start:offset]) + 1
+}
+
+// detectProtoNames returns whether the keys of the given entries use proto
+// field names rather than JSON names, or the given default if that cannot be
+// told.
+func detectProtoNames(entries []*editEntry, useProtoNames bool) bool {
+	for _, entry := range entries {
+		if entry.field == nil || entry.field.IsExtension() || entry.field.JSONName() == entry.field.TextName() {
+			continue
+		}
+		return entry.key.Value == entry.field.TextName()
+	}
+	return useProtoNames
+}
+
+// indentLines returns the given lines, indented to the given 1-based column and
+// ended with the line ending of the data. The first line is not indented unless
+// indentFirst is set.
+func (e *editor) indentLines(lines []string, column int, indentFirst bool) string {
+	indent := strings.Repeat(" ", column-1)
+	var result strings.Builder
+	for i, line := range lines {
+		if i > 0 || indentFirst {
+			result.WriteString(indent)
+		}
+		result.WriteString(line)
+		result.WriteString(e.newline)
+	}
+	return result.String()
+}
+
+func isBlockNode(node *yaml.Node, kind yaml.Kind) bool {
+	return node.Kind == kind && node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0
+}
+
+func isInt64Kind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestEdit(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Name     string
+		Input    string
+		Edit     func(msg *proto3.TestAllTypes)
+		Expected string
+	}{
+		{
+			Name:     "Unchanged",
+			Input:    "# Header.\nsingle_string:   'hi' # Greeting.\n",
+			Edit:     func(*proto3.TestAllTypes) {},
+			Expected: "# Header.\nsingle_string:   'hi' # Greeting.\n",
+		},
+		{
+			Name: "Scalars",
+			Input: `# The service config.
+single_string: "v1.2.3" # Bumped by automation.
+single_int64: 10
+single_int32: 1
+standalone_message:
+  # The number.
+  bb: 1 # Inline.
+`,
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.SingleString = "v1.2.4"
+				msg.SingleInt64 = 11
+				msg.SingleInt32 = 2
+				msg.StandaloneMessage.Bb = 42
+			},
+			Expected: `# The service config.
+single_string: "v1.2.4" # Bumped by automation.
+single_int64: 11
+single_int32: 2
+standalone_message:
+  # The number.
+  bb: 42 # Inline.
+`,
+		},
+		{
+			Name:  "CRLF",
+			Input: "# Header.\r\nsingle_int32: 1\r\nsingle_string: hi # Removed.\r\nrepeated_int32:\r\n  - 1\r\nstandalone_message:\r\n  bb: 1\r\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.SingleInt32 = 2
+				msg.SingleString = ""
+				msg.SingleBool = true
+				msg.RepeatedInt32 = append(msg.RepeatedInt32, 2)
+				msg.StandaloneMessage = &proto3.TestAllTypes_NestedMessage{Bb: 1}
+				msg.RepeatedString = []string{"a"}
+			},
+			Expected: "# Header.\r\nsingle_int32: 2\r\nsingle_bool: true\r\nrepeated_int32:\r\n  - 1\r\n  - 2\r\nstandalone_message:\r\n  bb: 1\r\nrepeated_string:\r\n  - a\r\n",
+		},
+		{
+			Name:  "InsertInDeclarationOrder",
+			Input: "# Header.\nsingle_int32: 1\n\n# Strings.\nsingle_string: hi\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.SingleInt64 = 2
+				msg.SingleBool = true
+				msg.SingleDuration = durationpb.New(1500000000)
+			},
+			Expected: "# Header.\nsingle_int32: 1\nsingle_int64: \"2\"\nsingle_bool: true\n\n# Strings.\nsingle_string: hi\nsingle_duration: 1.500s\n",
+		},
+		{
+			Name:     "InsertFirst",
+			Input:    "# The string.\nsingle_string: hi\n",
+			Edit:     func(msg *proto3.TestAllTypes) { msg.SingleInt32 = 1 },
+			Expected: "single_int32: 1\n# The string.\nsingle_string: hi\n",
+		},
+		{
+			Name:     "InsertJSONNames",
+			Input:    "singleInt32: 1\n",
+			Edit:     func(msg *proto3.TestAllTypes) { msg.StandaloneMessage = &proto3.TestAllTypes_NestedMessage{Bb: 1} },
+			Expected: "singleInt32: 1\nstandaloneMessage:\n  bb: 1\n",
+		},
+		{
+			Name:  "Delete",
+			Input: "single_int32: 1\n# The string.\nsingle_string: hi\nstandalone_message:\n  bb: 1\n\n# Trailing comment.\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.SingleString = ""
+				msg.StandaloneMessage = nil
+			},
+			Expected: "single_int32: 1\n\n# Trailing comment.\n",
+		},
+		{
+			Name:  "List",
+			Input: "repeated_string:\n  - a # First.\n  - b\nrepeated_int32: [1, 2]\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.RepeatedString = []string{"a", "c", "d"}
+				msg.RepeatedInt32 = []int32{1}
+			},
+			Expected: "repeated_string:\n  - a # First.\n  - c\n  - d\nrepeated_int32:\n  - 1\n",
+		},
+		{
+			Name:  "ListOfMessages",
+			Input: "repeated_nested_message:\n  - bb: 1\n    # Comment.\n  - bb: 2\n  - bb: 3\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.RepeatedNestedMessage = msg.RepeatedNestedMessage[:2]
+				msg.RepeatedNestedMessage[1].Bb = 4
+			},
+			Expected: "repeated_nested_message:\n  - bb: 1\n    # Comment.\n  - bb: 4\n",
+		},
+		{
+			Name:  "Map",
+			Input: "map_string_string:\n  b: x # Keep.\n  a: y\n  c: z\n",
+			Edit: func(msg *proto3.TestAllTypes) {
+				msg.MapStringString["a"] = "w"
+				delete(msg.MapStringString, "c")
+				msg.MapStringString["d"] = "v"
+			},
+			Expected: "map_string_string:\n  b: x # Keep.\n  a: w\n  d: v\n",
+		},
+		{
+			Name:     "FlowMapping",
+			Input:    "standalone_message: {bb: 1} # Lost.\nsingle_int32: 1\n",
+			Edit:     func(msg *proto3.TestAllTypes) { msg.StandaloneMessage.Bb = 2 },
+			Expected: "standalone_message:\n  bb: 2\nsingle_int32: 1\n",
+		},
+		{
+			Name:     "NoTrailingNewline",
+			Input:    "single_int32: 1",
+			Edit:     func(msg *proto3.TestAllTypes) { msg.SingleString = "hi" },
+			Expected: "single_int32: 1\nsingle_string: hi\n",
+		},
+		{
+			Name:  "Anchor",
+			Input: "standalone_message: &a\n  bb: 1\nrepeated_nested_message: [*a]\n",
+			Edit:  func(msg *proto3.TestAllTypes) { msg.StandaloneMessage.Bb = 2 },
+			// Editing a value shared through an anchor marshals the whole message.
+			Expected: "standaloneMessage:\n    bb: 2\nrepeatedNestedMessage:\n    - bb: 1\n",
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			msg := &proto3.TestAllTypes{}
+			require.NoError(t, Unmarshal([]byte(testCase.Input), msg))
+			testCase.Edit(msg)
+			actual, err := Edit([]byte(testCase.Input), msg)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.Expected, string(actual))
+
+			roundTrip := &proto3.TestAllTypes{}
+			require.NoError(t, Unmarshal(actual, roundTrip))
+			assert.True(t, proto.Equal(msg, roundTrip))
+		})
+	}
+}
+
+func TestEditError(t *testing.T) {
+	t.Parallel()
+	_, err := Edit([]byte("single_int32: [\n"), &proto3.TestAllTypes{})
+	require.ErrorContains(t, err, ":1:1 did not find expected node content")
+
+	_, err = Edit([]byte("unknown: 1\n"), &proto3.TestAllTypes{})
+	require.ErrorContains(t, err, `unknown field "unknown"`)
+}
+
+func TestEditSecrets(t *testing.T) {
+	t.Parallel()
+	references := &SecretReferences{}
+	input := "single_string: !secret db/password # From the vault.\nsingle_int32: 1\n"
+	msg := &proto3.TestAllTypes{}
+	options := UnmarshalOptions{SecretResolver: testSecrets, SecretReferences: references}
+	require.NoError(t, options.Unmarshal([]byte(input), msg))
+	assert.Equal(t, "hunter2", msg.GetSingleString())
+
+	msg.SingleInt32 = 2
+	msg.RepeatedString = []string{"a", "hunter2"}
+	actual, err := MarshalOptions{SecretReferences: references}.Edit([]byte(input), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "single_string: !secret db/password # From the vault.\nsingle_int32: 2\nrepeated_string:\n  - a\n  - hunter2\n", string(actual))
+
+	msg.SingleString = "changed"
+	actual, err = MarshalOptions{SecretReferences: references}.Edit([]byte(input), msg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(actual), "!secret")
+	assert.Contains(t, string(actual), "single_string: changed\n")
+}
+
+func TestEditRedact(t *testing.T) {
+	t.Parallel()
+	options := MarshalOptions{
+		Redact: true,
+		RedactField: func(field protoreflect.FieldDescriptor) bool {
+			return field.Name() == "single_string"
+		},
+	}
+	msg := &proto3.TestAllTypes{
+		SingleString:      "hunter2",
+		SingleInt32:       2,
+		StandaloneMessage: &proto3.TestAllTypes_NestedMessage{Bb: 1},
+	}
+	actual, err := options.Edit([]byte("# Header.\nsingle_int32: 1\n"), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "# Header.\nsingle_int32: 2\nsingle_string: '[REDACTED]'\nstandalone_message:\n  bb: 1\n", string(actual))
+
+	actual, err = options.Edit([]byte("single_string: plain # The password.\n"), &proto3.TestAllTypes{SingleString: "hunter2"})
+	require.NoError(t, err)
+	assert.Equal(t, "single_string: '[REDACTED]' # The password.\n", string(actual))
+}
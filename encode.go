@@ -16,11 +16,19 @@ package protoyaml
 
 import (
 	"bytes"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.yaml.in/yaml/v3"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
@@ -29,6 +37,11 @@ func Marshal(message proto.Message) ([]byte, error) {
 	return MarshalOptions{}.Marshal(message)
 }
 
+// MarshalNode marshals the given message to a YAML node.
+func MarshalNode(message proto.Message) (*yaml.Node, error) {
+	return MarshalOptions{}.MarshalNode(message)
+}
+
 // MarshalOptions is a configurable YAML format marshaller.
 //
 // It uses similar options to protojson.MarshalOptions.
@@ -46,70 +59,554 @@ type MarshalOptions struct {
 	UseEnumNumbers bool
 	// EmitUnpopulated specifies whether to emit unpopulated fields.
 	EmitUnpopulated bool
+	// EmitComments emits the leading comment of each field in its .proto file
+	// as a YAML comment above the field, if the file descriptor includes source
+	// code info. The descriptors of generated Go types do not include it.
+	EmitComments bool
 	// Resolver is used for looking up types when expanding google.protobuf.Any
 	// messages. If nil, this defaults to using protoregistry.GlobalTypes.
 	Resolver interface {
 		protoregistry.ExtensionTypeResolver
 		protoregistry.MessageTypeResolver
 	}
+	// SecretReferences, if not nil, are the references of the secrets resolved
+	// while unmarshaling the message. Values that were resolved from a secret,
+	// and have not changed, are emitted as `!secret` references.
+	SecretReferences *SecretReferences
+	// Redact replaces the values of fields with the debug_redact option, and of
+	// fields for which RedactField returns true, with "[REDACTED]". Use it to
+	// write messages to logs.
+	Redact bool
+	// RedactField returns true if the value of the given field should be
+	// redacted, in addition to fields with the debug_redact option. Only used if
+	// Redact is set.
+	RedactField func(field protoreflect.FieldDescriptor) bool
 }
 
 // Marshal marshals the given message to YAML using the options in MarshalOptions.
 // Do not depend on the output to be stable across different versions.
 func (o MarshalOptions) Marshal(message proto.Message) ([]byte, error) {
-	data, err := protojson.MarshalOptions{
-		AllowPartial:    o.AllowPartial,
-		UseProtoNames:   o.UseProtoNames,
-		UseEnumNumbers:  o.UseEnumNumbers,
-		EmitUnpopulated: o.EmitUnpopulated,
-		Resolver:        o.Resolver,
-	}.Marshal(message)
-	if err != nil {
-		return nil, err
-	}
-	yamlVal, err := jsonDataToYAML(data)
+	node, err := o.MarshalNode(message)
 	if err != nil {
 		return nil, err
 	}
 
-	// Write the JSON back out as YAML
 	buffer := &bytes.Buffer{}
 	encoder := yaml.NewEncoder(buffer)
 	encoder.SetIndent(o.Indent)
-	if err := encoder.Encode(yamlVal); err != nil {
+	if err := encoder.Encode(node); err != nil {
 		return nil, err
 	}
 	return buffer.Bytes(), nil
 }
 
-func jsonDataToYAML(data []byte) (any, error) {
-	// YAML unmarshal preserves the order of fields, but is more restrictive than JSON.
-	// Prefer it if the data is valid YAML.
-	jsonNode := &yaml.Node{}
-	if err := yaml.Unmarshal(data, jsonNode); err == nil {
-		if jsonNode.Kind == yaml.DocumentNode {
-			jsonNode = jsonNode.Content[0]
+// MarshalNode marshals the given message to a YAML node, such as to embed it in
+// a larger YAML document. The Indent option is not used.
+//
+// The node follows the protobuf JSON mapping, as produced by protojson, with
+// fields in the same order.
+func (o MarshalOptions) MarshalNode(message proto.Message) (*yaml.Node, error) {
+	if message == nil {
+		return newMappingNode(), nil
+	}
+	m := &marshaler{options: o, resolver: o.getResolver()}
+	node, err := m.marshalMessage(message.ProtoReflect(), "")
+	if err != nil {
+		return nil, err
+	}
+	if !o.AllowPartial {
+		if err := proto.CheckInitialized(message); err != nil {
+			return nil, err
 		}
-		clearStyle(jsonNode)
-		return jsonNode, nil
 	}
+	msgDesc := message.ProtoReflect().Descriptor()
+	if o.SecretReferences != nil {
+		o.replaceSecrets(node, msgDesc, "")
+	}
+	if o.Redact {
+		o.redactFields(node, msgDesc)
+	}
+	return node, nil
+}
 
-	// If the data is not valid YAML (e.g. a string contains control characters),
-	// fall back to JSON unmarshal, which loses field order, but is more permissive.
-	var jsonValue any
-	if err := json.Unmarshal(data, &jsonValue); err != nil {
-		return nil, err
+func (o MarshalOptions) getResolver() protoResolver {
+	if o.Resolver != nil {
+		return o.Resolver
 	}
-	return jsonValue, nil
+	return protoregistry.GlobalTypes
+}
+
+type marshaler struct {
+	options  MarshalOptions
+	resolver protoResolver
 }
 
-// clearStyle removes all style information from the node and its children.
+// marshalField is a field of a message, and its value.
 //
-// Without this, the returned YAML will look exactly like the JSON input.
-// TODO: Allow yaml style information to be specified in proto.
-func clearStyle(node *yaml.Node) {
-	node.Style = 0
-	for _, child := range node.Content {
-		clearStyle(child)
+// The value is invalid for unpopulated fields that are emitted as null.
+type marshalField struct {
+	field protoreflect.FieldDescriptor
+	value protoreflect.Value
+}
+
+// marshalMessage marshals the given message to a mapping node, or to the
+// special representation of a well-known type.
+//
+// If the typeURL is not empty, it is added as the first entry, as in the
+// representation of a google.protobuf.Any.
+func (m *marshaler) marshalMessage(message protoreflect.Message, typeURL string) (*yaml.Node, error) {
+	if marshal := findWKTMarshaler(message.Descriptor().FullName()); marshal != nil {
+		return marshal(m, message)
+	}
+	node := newMappingNode()
+	if typeURL != "" {
+		node.Content = append(node.Content, newStringNode(atTypeFieldName), newStringNode(typeURL))
+	}
+	for _, entry := range m.getFields(message) {
+		name := entry.field.JSONName()
+		if m.options.UseProtoNames {
+			name = entry.field.TextName()
+		}
+		value, err := m.marshalValue(entry.value, entry.field)
+		if err != nil {
+			return nil, err
+		}
+		applyFieldStyle(value, entry.field)
+		key := newStringNode(name)
+		if m.options.EmitComments {
+			key.HeadComment = getFieldComment(entry.field)
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
+
+// getFields returns the fields of the given message to marshal, in the order of
+// protojson: non-extension fields in declaration order, followed by extensions
+// sorted by full name.
+func (m *marshaler) getFields(message protoreflect.Message) []marshalField {
+	var fields []marshalField
+	if m.options.EmitUnpopulated {
+		fieldDescs := message.Descriptor().Fields()
+		for i := range fieldDescs.Len() {
+			field := fieldDescs.Get(i)
+			if message.Has(field) || field.ContainingOneof() != nil {
+				continue // Populated fields are added below, unset oneofs are omitted.
+			}
+			value := message.Get(field)
+			if field.HasPresence() {
+				value = protoreflect.Value{} // Emitted as null.
+			}
+			fields = append(fields, marshalField{field: field, value: value})
+		}
+	}
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fields = append(fields, marshalField{field: field, value: value})
+		return true
+	})
+	sort.Slice(fields, func(i, j int) bool {
+		return fieldLess(fields[i].field, fields[j].field)
+	})
+	return fields
+}
+
+// getFieldComment returns the leading comment of the given field in its .proto
+// file as a YAML comment, or an empty string if there is none.
+func getFieldComment(field protoreflect.FieldDescriptor) string {
+	file := field.ParentFile()
+	if file == nil {
+		return ""
+	}
+	comment := strings.TrimSuffix(file.SourceLocations().ByDescriptor(field).LeadingComments, "\n")
+	if comment == "" {
+		return ""
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("#"+line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fieldLess reports whether field x is marshaled before field y.
+func fieldLess(x, y protoreflect.FieldDescriptor) bool {
+	if x.IsExtension() != y.IsExtension() {
+		return !x.IsExtension()
+	}
+	if x.IsExtension() {
+		return x.FullName() < y.FullName()
+	}
+	return x.Index() < y.Index()
+}
+
+func (m *marshaler) marshalValue(value protoreflect.Value, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+	switch {
+	case field.IsList():
+		return m.marshalList(value.List(), field)
+	case field.IsMap():
+		return m.marshalMap(value.Map(), field)
+	default:
+		return m.marshalSingular(value, field)
+	}
+}
+
+func (m *marshaler) marshalList(list protoreflect.List, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: make([]*yaml.Node, 0, list.Len())}
+	for i := range list.Len() {
+		item, err := m.marshalSingular(list.Get(i), field)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+	}
+	return node, nil
+}
+
+// marshalMap marshals the given map to a mapping node, with the keys in
+// ascending order.
+func (m *marshaler) marshalMap(mapVal protoreflect.Map, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+	keys := make([]protoreflect.MapKey, 0, mapVal.Len())
+	mapVal.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sortMapKeys(keys, field.MapKey().Kind())
+	node := newMappingNode()
+	for _, key := range keys {
+		keyText := key.String()
+		if !utf8.ValidString(keyText) {
+			return nil, fmt.Errorf("field %v contains invalid UTF-8", field.FullName())
+		}
+		value, err := m.marshalSingular(mapVal.Get(key), field.MapValue())
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, newStringNode(keyText), value)
+	}
+	return node, nil
+}
+
+// sortMapKeys sorts the given map keys of the given kind in ascending order.
+func sortMapKeys(keys []protoreflect.MapKey, kind protoreflect.Kind) {
+	sort.Slice(keys, func(i, j int) bool {
+		switch kind {
+		case protoreflect.BoolKind:
+			return !keys[i].Bool() && keys[j].Bool()
+		case protoreflect.StringKind:
+			return keys[i].String() < keys[j].String()
+		case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+			return keys[i].Uint() < keys[j].Uint()
+		default:
+			return keys[i].Int() < keys[j].Int()
+		}
+	})
+}
+
+// marshalSingular marshals a value that is not a list or map. An invalid value
+// is marshaled as null.
+func (m *marshaler) marshalSingular(value protoreflect.Value, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+	if !value.IsValid() {
+		return newNullNode(), nil
+	}
+	switch kind := field.Kind(); kind {
+	case protoreflect.BoolKind:
+		return newScalarNode(strconv.FormatBool(value.Bool())), nil
+	case protoreflect.StringKind:
+		if !utf8.ValidString(value.String()) {
+			return nil, fmt.Errorf("field %v contains invalid UTF-8", field.FullName())
+		}
+		return newStringNode(value.String()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return newScalarNode(strconv.FormatInt(value.Int(), 10)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return newScalarNode(strconv.FormatUint(value.Uint(), 10)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64-bit integers are strings, as in JSON.
+		return newStringNode(value.String()), nil
+	case protoreflect.FloatKind:
+		return newFloatNode(value.Float(), 32), nil
+	case protoreflect.DoubleKind:
+		return newFloatNode(value.Float(), 64), nil
+	case protoreflect.BytesKind:
+		return newStringNode(base64.StdEncoding.EncodeToString(value.Bytes())), nil
+	case protoreflect.EnumKind:
+		if field.Enum().FullName() == "google.protobuf.NullValue" {
+			return newNullNode(), nil
+		}
+		enumValue := field.Enum().Values().ByNumber(value.Enum())
+		if m.options.UseEnumNumbers || enumValue == nil {
+			return newScalarNode(strconv.FormatInt(int64(value.Enum()), 10)), nil
+		}
+		return newStringNode(string(enumValue.Name())), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return m.marshalMessage(value.Message(), "")
+	default:
+		return nil, fmt.Errorf("%v has unknown kind: %v", field.FullName(), kind)
+	}
+}
+
+type wktMarshaler func(m *marshaler, message protoreflect.Message) (*yaml.Node, error)
+
+// findWKTMarshaler returns the marshaler for the well-known type with the given
+// name, or nil if the type has no special representation.
+func findWKTMarshaler(name protoreflect.FullName) wktMarshaler {
+	switch name {
+	case "google.protobuf.Any":
+		return marshalAnyMsg
+	case "google.protobuf.Duration":
+		return marshalDurationMsg
+	case "google.protobuf.Timestamp":
+		return marshalTimestampMsg
+	case "google.protobuf.BoolValue",
+		"google.protobuf.Int32Value",
+		"google.protobuf.Int64Value",
+		"google.protobuf.UInt32Value",
+		"google.protobuf.UInt64Value",
+		"google.protobuf.FloatValue",
+		"google.protobuf.DoubleValue",
+		"google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		return marshalWrapperMsg
+	case "google.protobuf.Struct":
+		return marshalStructMsg
+	case "google.protobuf.ListValue":
+		return marshalListValueMsg
+	case "google.protobuf.Value":
+		return marshalValueMsg
+	case "google.protobuf.FieldMask":
+		return marshalFieldMaskMsg
+	case "google.protobuf.Empty":
+		return marshalEmptyMsg
+	default:
+		return nil
+	}
+}
+
+// marshalAnyMsg marshals the message packed in the given Any, with its type URL
+// in an `@type` entry. Well-known types with a special representation are in a
+// `value` entry.
+func marshalAnyMsg(m *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	fields := message.Descriptor().Fields()
+	typeURLField := fields.ByName("type_url")
+	valueField := fields.ByName("value")
+	if !message.Has(typeURLField) {
+		if message.Has(valueField) {
+			return nil, errors.New("google.protobuf.Any: type_url is not set")
+		}
+		return newMappingNode(), nil
+	}
+	typeURL := message.Get(typeURLField).String()
+	msgType, err := m.resolver.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, fmt.Errorf("google.protobuf.Any: unable to resolve %#v: %w", typeURL, err)
+	}
+	packed := msgType.New()
+	err = proto.UnmarshalOptions{
+		AllowPartial: true, // Required fields are not checked inside an Any.
+		Resolver:     m.resolver,
+	}.Unmarshal(message.Get(valueField).Bytes(), packed.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("google.protobuf.Any: unable to unmarshal %#v: %w", typeURL, err)
+	}
+	marshal := findWKTMarshaler(msgType.Descriptor().FullName())
+	if marshal == nil {
+		return m.marshalMessage(packed, typeURL)
+	}
+	value, err := marshal(m, packed)
+	if err != nil {
+		return nil, err
+	}
+	node := newMappingNode()
+	node.Content = append(node.Content,
+		newStringNode(atTypeFieldName), newStringNode(typeURL),
+		newStringNode("value"), value,
+	)
+	return node, nil
+}
+
+const (
+	maxDurationSeconds = 315576000000
+	maxNanos           = 999999999
+)
+
+// marshalDurationMsg marshals the given Duration as a string in seconds, such
+// as "1.5s", with 0, 3, 6, or 9 fractional digits.
+func marshalDurationMsg(_ *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	fields := message.Descriptor().Fields()
+	secs := message.Get(fields.ByName("seconds")).Int()
+	nanos := message.Get(fields.ByName("nanos")).Int()
+	if secs < -maxDurationSeconds || secs > maxDurationSeconds {
+		return nil, fmt.Errorf("google.protobuf.Duration: seconds out of range %v", secs)
+	}
+	if nanos < -maxNanos || nanos > maxNanos {
+		return nil, fmt.Errorf("google.protobuf.Duration: nanos out of range %v", nanos)
+	}
+	if (secs > 0 && nanos < 0) || (secs < 0 && nanos > 0) {
+		return nil, errors.New("google.protobuf.Duration: signs of seconds and nanos do not match")
+	}
+	sign := ""
+	if secs < 0 || nanos < 0 {
+		sign, secs, nanos = "-", -secs, -nanos
+	}
+	text := trimFractionalZeros(fmt.Sprintf("%s%d.%09d", sign, secs, nanos))
+	return newStringNode(text + "s"), nil
+}
+
+// marshalTimestampMsg marshals the given Timestamp as an RFC 3339 string in
+// UTC, with 0, 3, 6, or 9 fractional digits.
+func marshalTimestampMsg(_ *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	fields := message.Descriptor().Fields()
+	secs := message.Get(fields.ByName("seconds")).Int()
+	nanos := message.Get(fields.ByName("nanos")).Int()
+	if secs < minTimestampSeconds || secs > maxTimestampSeconds {
+		return nil, fmt.Errorf("google.protobuf.Timestamp: seconds out of range %v", secs)
+	}
+	if nanos < 0 || nanos > maxNanos {
+		return nil, fmt.Errorf("google.protobuf.Timestamp: nanos out of range %v", nanos)
+	}
+	text := trimFractionalZeros(time.Unix(secs, nanos).UTC().Format("2006-01-02T15:04:05.000000000"))
+	return newStringNode(text + "Z"), nil
+}
+
+// trimFractionalZeros removes trailing zeros from the 9 fractional digits of
+// the given text, 3 at a time.
+func trimFractionalZeros(text string) string {
+	text = strings.TrimSuffix(text, "000")
+	text = strings.TrimSuffix(text, "000")
+	return strings.TrimSuffix(text, ".000")
+}
+
+func marshalWrapperMsg(m *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	field := message.Descriptor().Fields().ByName("value")
+	return m.marshalSingular(message.Get(field), field)
+}
+
+func marshalStructMsg(m *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	field := message.Descriptor().Fields().ByName("fields")
+	return m.marshalMap(message.Get(field).Map(), field)
+}
+
+func marshalListValueMsg(m *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	field := message.Descriptor().Fields().ByName("values")
+	return m.marshalList(message.Get(field).List(), field)
+}
+
+// marshalValueMsg marshals the field that is set in the kind of the given
+// Value.
+func marshalValueMsg(m *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	field := message.WhichOneof(message.Descriptor().Oneofs().ByName("kind"))
+	if field == nil {
+		return nil, errors.New("google.protobuf.Value: none of the oneof fields is set")
+	}
+	value := message.Get(field)
+	if field.Kind() == protoreflect.DoubleKind {
+		if number := value.Float(); math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, fmt.Errorf("google.protobuf.Value.number_value: invalid %v value", number)
+		}
+	}
+	return m.marshalSingular(value, field)
+}
+
+// marshalFieldMaskMsg marshals the paths of the given FieldMask as a comma
+// separated string of lowerCamelCase paths.
+func marshalFieldMaskMsg(_ *marshaler, message protoreflect.Message) (*yaml.Node, error) {
+	list := message.Get(message.Descriptor().Fields().ByName("paths")).List()
+	paths := make([]string, 0, list.Len())
+	for i := range list.Len() {
+		path := list.Get(i).String()
+		if !protoreflect.FullName(path).IsValid() {
+			return nil, fmt.Errorf("google.protobuf.FieldMask.paths contains invalid path: %#v", path)
+		}
+		camelCase := jsonCamelCase(path)
+		if jsonSnakeCase(camelCase) != path {
+			return nil, fmt.Errorf("google.protobuf.FieldMask.paths contains irreversible value %#v", path)
+		}
+		paths = append(paths, camelCase)
+	}
+	return newStringNode(strings.Join(paths, ",")), nil
+}
+
+func marshalEmptyMsg(*marshaler, protoreflect.Message) (*yaml.Node, error) {
+	return newMappingNode(), nil
+}
+
+// jsonCamelCase converts a snake_case path to lowerCamelCase, as in the JSON
+// mapping of google.protobuf.FieldMask.
+func jsonCamelCase(text string) string {
+	var result strings.Builder
+	wasUnderscore := false
+	for i := range len(text) {
+		char := text[i]
+		if char != '_' {
+			if wasUnderscore && 'a' <= char && char <= 'z' {
+				char -= 'a' - 'A'
+			}
+			result.WriteByte(char)
+		}
+		wasUnderscore = char == '_'
+	}
+	return result.String()
+}
+
+// jsonSnakeCase converts a lowerCamelCase path to snake_case.
+func jsonSnakeCase(text string) string {
+	var result strings.Builder
+	for i := range len(text) {
+		char := text[i]
+		if 'A' <= char && char <= 'Z' {
+			result.WriteByte('_')
+			char += 'a' - 'A'
+		}
+		result.WriteByte(char)
+	}
+	return result.String()
+}
+
+func newMappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newStringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func newNullNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// newScalarNode returns a plain scalar node with the tag that YAML resolves for
+// the given value, such as `!!int` or `!!bool`.
+func newScalarNode(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	node.Tag = node.ShortTag()
+	return node
+}
+
+// newFloatNode returns a node for the given floating point number, formatted
+// as in JSON. NaN and infinities are strings.
+func newFloatNode(value float64, bitSize int) *yaml.Node {
+	switch {
+	case math.IsNaN(value):
+		return newStringNode("NaN")
+	case math.IsInf(value, 1):
+		return newStringNode("Infinity")
+	case math.IsInf(value, -1):
+		return newStringNode("-Infinity")
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	text := strconv.FormatFloat(value, format, -1, bitSize)
+	if format == 'e' {
+		// Use a single digit exponent when possible, such as 1e-07 -> 1e-7.
+		if n := len(text); n >= 4 && text[n-4] == 'e' && text[n-3] == '-' && text[n-2] == '0' {
+			text = text[:n-2] + text[n-1:]
+		}
 	}
+	return newScalarNode(text)
 }
@@ -15,10 +15,29 @@
 package protoyaml
 
 import (
+	"bytes"
 	"math"
 	"testing"
 
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
 	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"buf.build/go/protoyaml/internal/protoyamltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestFloatJsonEncoding(t *testing.T) {
@@ -151,3 +170,188 @@ func TestIndentSize(t *testing.T) {
 		t.Fatalf("Expected 2 space indent, got %q", string(data))
 	}
 }
+
+func TestMarshalMatchesProtoJSON(t *testing.T) {
+	t.Parallel()
+	messages := []proto.Message{
+		nil,
+		&proto3.TestAllTypes{},
+		&proto3.TestAllTypes{
+			SingleInt32:  -1,
+			SingleInt64:  1 << 40,
+			SingleFloat:  1e-7,
+			SingleDouble: 1e21,
+			SingleString: "line one\nline two",
+			SingleBytes:  []byte("bytes"),
+			SingleAny:    mustNewAny(t, &fieldmaskpb.FieldMask{Paths: []string{"single_int32", "nested.single_bool"}}),
+			RepeatedAny: []*anypb.Any{
+				mustNewAny(t, &proto3.TestAllTypes{SingleBool: true}),
+				mustNewAny(t, &emptypb.Empty{}),
+				mustNewAny(t, mustNewAny(t, durationpb.New(-1500000000))),
+			},
+			SingleDuration:  &durationpb.Duration{Seconds: 1, Nanos: 1000},
+			SingleTimestamp: &timestamppb.Timestamp{Seconds: 1700000000, Nanos: 120000000},
+			SingleValue:     structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewNullValue()}}),
+			MapStringString: map[string]string{"b": "2", "a": "1", "true": "yes"},
+			MapBoolBool:     map[bool]bool{true: false, false: true},
+		},
+	}
+	withExtensions := &testv1.Proto2Test{Values: []*testv1.Proto2TestValue{{}}}
+	proto.SetExtension(withExtensions, testv1.E_P2TRepeatedStringExt, []string{"a", "b"})
+	proto.SetExtension(withExtensions, testv1.E_P2TStringExt, "c")
+	messages = append(messages, withExtensions)
+	for _, msg := range protoyamltest.InterestingTestValues() {
+		messages = append(messages, msg)
+	}
+	for i := range int64(20) {
+		msg := &proto3.TestAllTypes{}
+		protoyamltest.PopulateMessage(msg, i)
+		messages = append(messages, msg)
+	}
+	for _, options := range []MarshalOptions{
+		{},
+		{Indent: 2, UseProtoNames: true, UseEnumNumbers: true},
+		{EmitUnpopulated: true},
+	} {
+		for _, msg := range messages {
+			expected, ok := marshalWithProtoJSON(t, options, msg)
+			if !ok {
+				continue
+			}
+			actual, err := options.Marshal(msg)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		}
+	}
+}
+
+func TestMarshalFieldOrder(t *testing.T) {
+	t.Parallel()
+	// The JSON encoding of the control character is not valid YAML.
+	msg := &proto3.TestAllTypes{
+		SingleString: "\x7f",
+		SingleBool:   true,
+		SingleInt32:  1,
+	}
+	data, err := MarshalOptions{Indent: 2}.Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, "singleInt32: 1\nsingleBool: true\nsingleString: \"\\x7F\"\n", string(data))
+}
+
+// marshalWithProtoJSON marshals the given message to JSON and converts it to
+// YAML. Returns false if protojson fails or the JSON is not valid YAML.
+func marshalWithProtoJSON(t *testing.T, options MarshalOptions, msg proto.Message) ([]byte, bool) {
+	t.Helper()
+	data, err := protojson.MarshalOptions{
+		AllowPartial:    options.AllowPartial,
+		UseProtoNames:   options.UseProtoNames,
+		UseEnumNumbers:  options.UseEnumNumbers,
+		EmitUnpopulated: options.EmitUnpopulated,
+	}.Marshal(msg)
+	if err != nil {
+		_, err := options.Marshal(msg)
+		assert.Error(t, err)
+		return nil, false
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, false
+	}
+	clearNodeStyle(&node)
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(options.Indent)
+	require.NoError(t, encoder.Encode(node.Content[0]))
+	return buffer.Bytes(), true
+}
+
+func clearNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
+
+func mustNewAny(t *testing.T, msg proto.Message) *anypb.Any {
+	t.Helper()
+	anyVal, err := anypb.New(msg)
+	require.NoError(t, err)
+	return anyVal
+}
+
+func TestMarshalNode(t *testing.T) {
+	t.Parallel()
+	node, err := MarshalNode(&proto3.TestAllTypes{SingleInt32: 1, SingleString: "hi"})
+	require.NoError(t, err)
+	document := &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "name"},
+			{Kind: yaml.ScalarNode, Value: "service"},
+			{Kind: yaml.ScalarNode, Value: "config"},
+			node,
+		},
+	}
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	require.NoError(t, encoder.Encode(document))
+	assert.Equal(t, "name: service\nconfig:\n  singleInt32: 1\n  singleString: hi\n", buffer.String())
+
+	actual := &proto3.TestAllTypes{}
+	require.NoError(t, UnmarshalNode(node, actual))
+	assert.Equal(t, int32(1), actual.GetSingleInt32())
+	assert.Equal(t, "hi", actual.GetSingleString())
+}
+
+func TestEmitComments(t *testing.T) {
+	t.Parallel()
+	msgDesc := (&proto3.TestAllTypes{}).ProtoReflect().Descriptor()
+	nestedDesc := (&proto3.TestAllTypes_NestedMessage{}).ProtoReflect().Descriptor()
+	fileProto := protodesc.ToFileDescriptorProto(msgDesc.ParentFile())
+	comment := func(text string, path ...int32) *descriptorpb.SourceCodeInfo_Location {
+		return &descriptorpb.SourceCodeInfo_Location{Path: path, Span: []int32{0, 0, 0}, LeadingComments: proto.String(text)}
+	}
+	fieldComment := func(text string, name protoreflect.Name) *descriptorpb.SourceCodeInfo_Location {
+		field := msgDesc.Fields().ByName(name)
+		return comment(text, 4, int32(msgDesc.Index()), 2, int32(field.Index()))
+	}
+	fileProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{Location: []*descriptorpb.SourceCodeInfo_Location{
+		fieldComment(" A string.\n", "single_string"),
+		fieldComment(" The messages.\n\n Each is nested.\n", "repeated_nested_message"),
+		fieldComment(" Messages by name.\n", "map_string_message"),
+		comment(" The value.\n", 4, int32(msgDesc.Index()), 3, int32(nestedDesc.Index()), 2, 0),
+	}}
+	file, err := protodesc.NewFile(fileProto, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	data := []byte("singleString: root\nrepeatedNestedMessage:\n  - bb: 1\nmapStringMessage:\n  a:\n    bb: 2\n")
+	options := MarshalOptions{Indent: 2, EmitComments: true}
+
+	msg := dynamicpb.NewMessage(file.Messages().ByName(msgDesc.Name()))
+	require.NoError(t, Unmarshal(data, msg))
+	actual, err := options.Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, `# A string.
+singleString: root
+# The messages.
+#
+# Each is nested.
+repeatedNestedMessage:
+  - # The value.
+    bb: 1
+# Messages by name.
+mapStringMessage:
+  a:
+    # The value.
+    bb: 2
+`, string(actual))
+
+	// Without source code info, there are no comments.
+	actual, err = options.Marshal(&proto3.TestAllTypes{
+		SingleString:          "root",
+		RepeatedNestedMessage: []*proto3.TestAllTypes_NestedMessage{{Bb: 1}},
+		MapStringMessage:      map[string]*proto3.TestAllTypes_NestedMessage{"a": {Bb: 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(actual))
+}
@@ -15,46 +15,221 @@
 package protoyaml
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"buf.build/go/protovalidate"
 	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-// nodeError is an error that occurred while processing a specific yaml.Node.
-type nodeError struct {
-	Node  *yaml.Node
-	Path  string
-	line  string
-	cause error
+// Error is an error found at a specific location in the YAML input.
+type Error struct {
+	// Path is the path of the YAML input, as given by UnmarshalOptions.Path.
+	Path string
+	// Line and Column are the 1-based position of the start of the node that
+	// caused the error.
+	Line, Column int
+	// EndLine and EndColumn are the 1-based position just past the end of the
+	// node, if known. Otherwise, they are equal to Line and Column.
+	EndLine, EndColumn int
+	// FieldPath is the path to the field that was being unmarshaled, such as
+	// `values[2].single_bool`. Empty if the error is not specific to a field.
+	FieldPath string
+	// Descriptor is the descriptor of the message that was being unmarshaled,
+	// if any.
+	Descriptor protoreflect.MessageDescriptor
+	// Cause is the underlying error.
+	Cause error
+
+	line string
+	// The aliases the node was expanded from, innermost first.
+	aliases []*sourceSite
+	// The include tags the node's file was included from, innermost first.
+	includes []*sourceSite
+}
+
+// sourceSite is the location of an alias or include tag that a node was
+// expanded from.
+type sourceSite struct {
+	Path string
+	Node *yaml.Node
+	line string
 }
 
-func (n *nodeError) Unwrap() error {
-	return n.cause
+func (e *Error) Unwrap() error {
+	return e.Cause
 }
 
-// DetailedError returns an error message that includes the path and a code snippet, if
+// Error returns an error message that includes the path and a code snippet, if
 // the lines of the source code are provided.
-func (n *nodeError) Error() string {
+func (e *Error) Error() string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s:%d:%d %s\n", e.Path, e.Line, e.Column, e.Cause.Error())
+	writeSnippet(&result, e.Line, e.Column, e.line)
+	for _, alias := range e.aliases {
+		fmt.Fprintf(&result, "%s:%d:%d in expansion of alias *%s\n", alias.Path, alias.Node.Line, alias.Node.Column, alias.Node.Value)
+		writeSnippet(&result, alias.Node.Line, alias.Node.Column, alias.line)
+	}
+	for _, include := range e.includes {
+		fmt.Fprintf(&result, "%s:%d:%d in inclusion of %s\n", include.Path, include.Node.Line, include.Node.Column, include.Node.Value)
+		writeSnippet(&result, include.Node.Line, include.Node.Column, include.line)
+	}
+	return result.String()
+}
+
+// writeSnippet writes the given source line with a marker pointing at the column.
+func writeSnippet(result *strings.Builder, lineNum int, column int, line string) {
+	if line == "" {
+		return
+	}
+	prefix := fmt.Sprintf("%4d", lineNum)
+	fmt.Fprintf(result, "%s | %s\n", prefix, line)
+	marker := strings.Repeat(".", column-1) + "^"
+	fmt.Fprintf(result, "%s | %s\n", prefix, marker)
+}
+
+// ErrorList is a list of errors found while unmarshaling YAML input.
+//
+// Use errors.As to retrieve the ErrorList from an error returned by
+// [UnmarshalOptions.Unmarshal].
+type ErrorList []*Error
+
+// Error returns the messages of all errors, separated by newlines.
+func (l ErrorList) Error() string {
 	var result strings.Builder
-	fmt.Fprintf(&result, "%s:%d:%d %s\n", n.Path, n.Node.Line, n.Node.Column, n.Unwrap().Error())
-	if n.line != "" {
-		lineNum := fmt.Sprintf("%4d", n.Node.Line)
-		fmt.Fprintf(&result, "%s | %s\n", lineNum, n.line)
-		marker := strings.Repeat(".", n.Node.Column-1) + "^"
-		fmt.Fprintf(&result, "%s | %s\n", lineNum, marker)
+	for i, err := range l {
+		if i > 0 {
+			result.WriteByte('\n')
+		}
+		result.WriteString(err.Error())
 	}
 	return result.String()
 }
 
-// violationError is singe validation violation.
-type violationError struct {
+// Unwrap returns the errors in the list.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ViolationError is a single validation violation, reported by a Validator.
+type ViolationError struct {
 	Violation *validate.Violation
 }
 
 // Error prints the field path, message, and constraint ID.
-func (v *violationError) Error() string {
+func (v *ViolationError) Error() string {
 	return protovalidate.FieldPathString(v.Violation.GetField()) + ": " + v.Violation.GetMessage() + " (" + v.Violation.GetRuleId() + ")"
 }
+
+// newError returns an Error for the given node.
+//
+// The line is the source of the line that contains the node, if known.
+func newError(path string, node *yaml.Node, line string, cause error) *Error {
+	endLine, endColumn := findNodeEnd(node, line)
+	return &Error{
+		Path:      path,
+		Line:      node.Line,
+		Column:    node.Column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Cause:     cause,
+		line:      line,
+	}
+}
+
+// newSyntaxError returns an error positioned at the location reported by the
+// given yaml.v3 parser error.
+//
+// The parser only reports a line number, and not always, so the error points at
+// the first non-blank character of the line. Unknown anchors are located by
+// searching for the alias. Otherwise, the error points at the start of the
+// data, which starts after lineOffset lines of the original input.
+func (o UnmarshalOptions) newSyntaxError(err error, data []byte, lineOffset int) error {
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	lines := strings.Split(string(data), "\n")
+	lineNum, column := 1, 0
+	if rest, ok := strings.CutPrefix(message, "line "); ok {
+		if num, msg, ok := strings.Cut(rest, ": "); ok {
+			if parsed, parseErr := strconv.Atoi(num); parseErr == nil && parsed > 0 {
+				lineNum = min(parsed, len(lines))
+				message = msg
+			}
+		}
+	} else if name, ok := strings.CutPrefix(message, "unknown anchor '"); ok {
+		name = strings.TrimSuffix(name, "' referenced")
+		for i, line := range lines {
+			if index := strings.Index(line, "*"+name); index >= 0 {
+				lineNum, column = i+1, len([]rune(line[:index]))+1
+				break
+			}
+		}
+	}
+	line := strings.TrimSuffix(lines[lineNum-1], "\r")
+	if column == 0 {
+		column = len([]rune(line)) - len([]rune(strings.TrimLeft(line, " \t"))) + 1
+	}
+	position := &yaml.Node{Line: lineOffset + lineNum, Column: column}
+	return ErrorList{newError(o.Path, position, line, errors.New(message))}
+}
+
+// findNodeEnd returns the position just past the end of the given node, if it
+// is a scalar that ends on the given source line. Otherwise, returns the start
+// of the node.
+func findNodeEnd(node *yaml.Node, line string) (int, int) {
+	if node.Kind != yaml.ScalarNode || line == "" || strings.ContainsAny(node.Value, "\r\n") {
+		return node.Line, node.Column
+	}
+	text := []rune(line)
+	start := node.Column - 1
+	if start < 0 || start >= len(text) {
+		return node.Line, node.Column
+	}
+	if text[start] == '!' && node.Style&yaml.TaggedStyle != 0 {
+		// Skip the tag.
+		for start < len(text) && text[start] != ' ' {
+			start++
+		}
+		for start < len(text) && text[start] == ' ' {
+			start++
+		}
+	}
+	end := start
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		end = findQuoteEnd(text, start, '"', '\\')
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		end = findQuoteEnd(text, start, '\'', '\'')
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return node.Line, node.Column
+	default:
+		end += len([]rune(node.Value))
+	}
+	if end > len(text) {
+		return node.Line, node.Column
+	}
+	return node.Line, end + 1
+}
+
+// findQuoteEnd returns the index just past the closing quote of the quoted
+// string that starts at the given index.
+func findQuoteEnd(text []rune, start int, quote rune, escape rune) int {
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case text[i] == escape && escape != quote:
+			i++ // Skip the escaped character.
+		case text[i] == quote && escape == quote && i+1 < len(text) && text[i+1] == quote:
+			i++ // An escaped quote.
+		case text[i] == quote:
+			return i + 1
+		}
+	}
+	return len(text) + 1
+}
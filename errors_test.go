@@ -0,0 +1,221 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"buf.build/go/protovalidate"
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type testValidator struct {
+	validator protovalidate.Validator
+}
+
+func (v *testValidator) Validate(message proto.Message) error {
+	return v.validator.Validate(message)
+}
+
+func TestErrorList(t *testing.T) {
+	t.Parallel()
+	data := []byte(`values:
+  - single_bool: "true"
+  - map_string_int32: {a: b}
+    repeated_string: [a, [b]]
+    single_nested_message: {bb: "0x"}
+`)
+	err := UnmarshalOptions{Path: "test.yaml"}.Unmarshal(data, &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 4)
+	for _, testCase := range []struct {
+		Line, Column, EndColumn int
+		FieldPath               string
+		Descriptor              proto.Message
+	}{
+		{Line: 2, Column: 18, EndColumn: 24, FieldPath: "values[0].single_bool", Descriptor: &proto3.TestAllTypes{}},
+		{Line: 3, Column: 27, EndColumn: 28, FieldPath: `values[1].map_string_int32["a"]`, Descriptor: &proto3.TestAllTypes{}},
+		{Line: 4, Column: 26, EndColumn: 26, FieldPath: "values[1].repeated_string[1]", Descriptor: &proto3.TestAllTypes{}},
+		{Line: 5, Column: 33, EndColumn: 37, FieldPath: "values[1].single_nested_message.bb", Descriptor: &proto3.TestAllTypes_NestedMessage{}},
+	} {
+		actual := errList[0]
+		errList = errList[1:]
+		assert.Equal(t, "test.yaml", actual.Path)
+		assert.Equal(t, testCase.Line, actual.Line)
+		assert.Equal(t, testCase.Column, actual.Column)
+		assert.Equal(t, testCase.Line, actual.EndLine)
+		assert.Equal(t, testCase.EndColumn, actual.EndColumn)
+		assert.Equal(t, testCase.FieldPath, actual.FieldPath)
+		assert.Equal(t, testCase.Descriptor.ProtoReflect().Descriptor(), actual.Descriptor)
+		require.Error(t, actual.Cause)
+	}
+}
+
+func TestViolationError(t *testing.T) {
+	t.Parallel()
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+	options := UnmarshalOptions{
+		Validator: &testValidator{validator: validator},
+	}
+	data := []byte(`cases:
+  - float_gt_lt: 10.5
+  - float_gt_lt: 1
+    string_map: {abc: xyz}
+`)
+	err = options.Unmarshal(data, &testv1.ValidateTest{})
+	var violationErr *ViolationError
+	require.ErrorAs(t, err, &violationErr)
+	assert.Equal(t, "float.gt_lt", violationErr.Violation.GetRuleId())
+
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 2)
+	assert.Equal(t, "cases[0].float_gt_lt", errList[0].FieldPath)
+	assert.Equal(t, 2, errList[0].Line)
+	assert.Equal(t, 18, errList[0].Column)
+	assert.Equal(t, 22, errList[0].EndColumn)
+	assert.Equal(t, (&testv1.ValidateTestCase{}).ProtoReflect().Descriptor(), errList[0].Descriptor)
+	assert.Equal(t, `cases[1].string_map["abc"]`, errList[1].FieldPath)
+	assert.Equal(t, 4, errList[1].Line)
+	require.ErrorAs(t, errList[1], &violationErr)
+	assert.Equal(t, "string.pattern", violationErr.Violation.GetRuleId())
+}
+
+func TestErrorListMessage(t *testing.T) {
+	t.Parallel()
+	data := []byte("values:\n  - wat: 1\n  - single_bool: 1\n")
+	err := UnmarshalOptions{Path: "test.yaml"}.Unmarshal(data, &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	// The message is the same as joining the individual errors.
+	assert.Equal(t, errors.Join(errList[0], errList[1]).Error(), err.Error())
+}
+
+func TestSyntaxError(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Data     string
+		Expected string
+	}{
+		{
+			Data: "values: 1\n single_bool: true\n",
+			Expected: `test.yaml:2:2 mapping values are not allowed in this context
+   2 |  single_bool: true
+   2 | .^
+`,
+		},
+		{
+			Data: "values:\n\t- single_bool: true\n",
+			Expected: `test.yaml:2:2 found character that cannot start any token
+   2 | 	- single_bool: true
+   2 | .^
+`,
+		},
+		{
+			Data: "values:\n  - single_int32: *x\n",
+			Expected: `test.yaml:2:19 unknown anchor 'x' referenced
+   2 |   - single_int32: *x
+   2 | ..................^
+`,
+		},
+		{
+			Data: "values: [a, b]]\n",
+			Expected: `test.yaml:1:1 did not find expected key
+   1 | values: [a, b]]
+   1 | ^
+`,
+		},
+	} {
+		err := UnmarshalOptions{Path: "test.yaml"}.Unmarshal([]byte(testCase.Data), &testv1.Proto3Test{})
+		var errList ErrorList
+		require.ErrorAs(t, err, &errList, testCase.Data)
+		require.Len(t, errList, 1)
+		assert.Equal(t, testCase.Expected, err.Error())
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	t.Parallel()
+	data := "values:\n  - single_bool: true\n---\nvalues:\n  - single_bool: 'true\n"
+	decoder := NewDecoder(strings.NewReader(data), UnmarshalOptions{Path: "test.yaml"})
+	require.NoError(t, decoder.Decode(&testv1.Proto3Test{}))
+	err := decoder.Decode(&testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 1)
+	assert.Equal(t, 5, errList[0].Line)
+	assert.Equal(t, "found unexpected end of stream", errList[0].Cause.Error())
+}
+
+func TestRequiredFieldError(t *testing.T) {
+	t.Parallel()
+	data := []byte(`uninterpreted_option:
+  - name:
+      - name_part: foo
+      - is_extension: true
+    wat: 1
+  - name: [{}]
+`)
+	err := UnmarshalOptions{Path: "test.yaml"}.Unmarshal(data, &descriptorpb.MessageOptions{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 5)
+	namePartDesc := (&descriptorpb.UninterpretedOption_NamePart{}).ProtoReflect().Descriptor()
+	for _, testCase := range []struct {
+		Line, Column int
+		FieldPath    string
+		Message      string
+	}{
+		{Line: 3, Column: 9, FieldPath: "uninterpreted_option[0].name[0].is_extension", Message: "required field google.protobuf.UninterpretedOption.NamePart.is_extension not set"},
+		{Line: 4, Column: 9, FieldPath: "uninterpreted_option[0].name[1].name_part", Message: "required field google.protobuf.UninterpretedOption.NamePart.name_part not set"},
+		{Line: 5, Column: 5, FieldPath: "uninterpreted_option[0]", Message: `unknown field "wat", expected one of [name identifier_value positive_int_value negative_int_value double_value string_value aggregate_value ...]`},
+		{Line: 6, Column: 12, FieldPath: "uninterpreted_option[1].name[0].name_part", Message: "required field google.protobuf.UninterpretedOption.NamePart.name_part not set"},
+		{Line: 6, Column: 12, FieldPath: "uninterpreted_option[1].name[0].is_extension", Message: "required field google.protobuf.UninterpretedOption.NamePart.is_extension not set"},
+	} {
+		actual := errList[0]
+		errList = errList[1:]
+		assert.Equal(t, testCase.Line, actual.Line)
+		assert.Equal(t, testCase.Column, actual.Column)
+		assert.Equal(t, testCase.FieldPath, actual.FieldPath)
+		assert.Equal(t, testCase.Message, actual.Cause.Error())
+		if strings.HasPrefix(testCase.Message, "required") {
+			assert.Equal(t, namePartDesc, actual.Descriptor)
+		}
+	}
+
+	// Partial messages are allowed when requested.
+	err = UnmarshalOptions{AllowPartial: true}.Unmarshal([]byte("uninterpreted_option: [{name: [{}]}]"), &descriptorpb.MessageOptions{})
+	require.NoError(t, err)
+
+	// An empty document is reported at its start, as a null document.
+	for _, input := range []string{"", "# Only a comment.\n", "null\n"} {
+		err = UnmarshalOptions{Path: "test.yaml"}.Unmarshal([]byte(input), &descriptorpb.UninterpretedOption_NamePart{})
+		require.ErrorAs(t, err, &errList, input)
+		require.Len(t, errList, 2, input)
+		assert.Equal(t, "test.yaml:1:1 required field google.protobuf.UninterpretedOption.NamePart.name_part not set", strings.SplitN(errList[0].Error(), "\n", 2)[0])
+		assert.Equal(t, "name_part", errList[0].FieldPath)
+		assert.Equal(t, "is_extension", errList[1].FieldPath)
+		assert.Equal(t, namePartDesc, errList[1].Descriptor)
+	}
+}
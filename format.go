@@ -0,0 +1,296 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Format rewrites the given YAML documents of the given message in canonical
+// form, using the default MarshalOptions.
+func Format(data []byte, message proto.Message) ([]byte, error) {
+	return MarshalOptions{}.Format(data, message)
+}
+
+// Format rewrites the given YAML documents of the given message in canonical
+// form, as written by Marshal: fields in declaration order and named as set by
+// UseProtoNames, enums by name, durations and timestamps in their standard
+// format, and the indentation set by Indent. Each document of a `---`
+// separated stream is formatted, and the message is set to the content of the
+// last document.
+//
+// Comments are kept with the values they belong to. The comments of entries
+// that are removed, such as fields set to their default value, are dropped,
+// except for the head comment of the first entry, such as the header of a
+// file, which stays at the top. Directives such as `%YAML 1.2` and documents
+// without content are kept as they are. Documents with anchors,
+// aliases or custom tags such as `!include` are not formatted, as the same
+// document cannot be written back.
+func (o MarshalOptions) Format(data []byte, message proto.Message) ([]byte, error) {
+	decoder := NewDecoder(bytes.NewReader(data), UnmarshalOptions{})
+	var result bytes.Buffer
+	for {
+		document, lineOffset, err := decoder.nextDocument()
+		switch {
+		case errors.Is(err, io.EOF):
+			return result.Bytes(), nil
+		case err != nil:
+			return nil, err
+		}
+		formatted, err := o.formatDocument(document, lineOffset, message)
+		switch {
+		case err != nil:
+			return nil, err
+		case formatted == nil:
+			result.Write(document) // Nothing to format.
+		case result.Len() > 0 && formatted[0] == '%':
+			// Directives must follow the end of the previous document.
+			result.WriteString("...\n")
+			result.Write(formatted)
+		default:
+			if result.Len() > 0 {
+				result.WriteString("---\n")
+			}
+			result.Write(formatted)
+		}
+	}
+}
+
+// formatDocument formats the given document, which starts after the given
+// number of lines of the stream. Returns nil if the document has no content.
+func (o MarshalOptions) formatDocument(data []byte, lineOffset int, message proto.Message) ([]byte, error) {
+	var document yaml.Node
+	if err := parseYAML(data, &document); err != nil {
+		return nil, UnmarshalOptions{}.newSyntaxError(err, data, lineOffset)
+	}
+	if isEmptyDocument(&document) {
+		return nil, nil
+	}
+	shiftLines(&document, lineOffset)
+	unm := &unmarshaler{
+		options:    UnmarshalOptions{Resolver: o.Resolver, AllowPartial: o.AllowPartial},
+		lines:      strings.Split(string(data), "\n"),
+		lineOffset: lineOffset,
+	}
+	if unm.checkFormattable(&document); len(unm.errors) > 0 {
+		return nil, unm.errorList()
+	}
+	proto.Reset(message)
+	if err := unm.options.unmarshalDocument(&document, message, data, lineOffset); err != nil {
+		return nil, err
+	}
+	node, err := o.MarshalNode(message)
+	if err != nil {
+		return nil, err
+	}
+	unm.copyMessageComments(document.Content[0], node, message.ProtoReflect().Descriptor())
+	keepHeaderComment(document.Content[0], node)
+	result := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+	copyComments(&document, result)
+
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(o.Indent)
+	buffer.Write(getDirectives(data))
+	if buffer.Len() > 0 {
+		buffer.WriteString("---\n")
+	}
+	if err := encoder.Encode(result); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// getDirectives returns the directive lines at the start of the given
+// document, such as `%YAML 1.2`.
+func getDirectives(data []byte) []byte {
+	var directives []byte
+	for _, line := range bytes.SplitAfter(data, []byte{'\n'}) {
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(line, []byte{'%'}):
+			directives = append(directives, bytes.TrimRight(line, " \t\r\n")...)
+			directives = append(directives, '\n')
+		case len(trimmed) > 0 && trimmed[0] != '#':
+			return directives
+		}
+	}
+	return directives
+}
+
+// keepHeaderComment keeps the head comment of the first entry of the given
+// mapping, such as the header of a file, at the top of its formatted mapping.
+func keepHeaderComment(from, to *yaml.Node) {
+	if from.Kind != yaml.MappingNode || to.Kind != yaml.MappingNode || len(from.Content) == 0 || len(to.Content) == 0 {
+		return
+	}
+	header := from.Content[0].HeadComment
+	if header == "" || to.Content[0].HeadComment == header {
+		return
+	}
+	for i := 2; i < len(to.Content); i += 2 {
+		if to.Content[i].HeadComment == header {
+			to.Content[i].HeadComment = ""
+			break
+		}
+	}
+	if to.Content[0].HeadComment != "" {
+		header += "\n" + to.Content[0].HeadComment
+	}
+	to.Content[0].HeadComment = header
+}
+
+// checkFormattable reports an error for each node of the given document that
+// cannot be written back by Format.
+func (u *unmarshaler) checkFormattable(node *yaml.Node) {
+	switch {
+	case node.Kind == yaml.AliasNode:
+		u.addErrorf(node, "cannot format alias *%s", node.Value)
+		return
+	case node.Anchor != "":
+		u.addErrorf(node, "cannot format anchor &%s", node.Anchor)
+	case strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!"):
+		u.addErrorf(node, "cannot format tag %s", node.Tag)
+	}
+	for _, child := range node.Content {
+		u.checkFormattable(child)
+	}
+}
+
+// copyMessageComments copies the comments of the given mapping of a message
+// to the corresponding nodes of its formatted mapping.
+func (u *unmarshaler) copyMessageComments(from, to *yaml.Node, msgDesc protoreflect.MessageDescriptor) {
+	if findWKTMarshaler(msgDesc.FullName()) != nil {
+		copyNodeComments(from, to)
+		return
+	}
+	copyComments(from, to)
+	if from.Kind != yaml.MappingNode || to.Kind != yaml.MappingNode {
+		return
+	}
+	targets := make(map[protoreflect.FullName]int)
+	for i := 0; i+1 < len(to.Content); i += 2 {
+		if field, err := u.findField(to.Content[i].Value, msgDesc); err == nil {
+			targets[field.FullName()] = i
+		}
+	}
+	for i := 0; i+1 < len(from.Content); i += 2 {
+		key := from.Content[i]
+		keyText := key.Value
+		if key.Kind == yaml.SequenceNode && len(key.Content) == 1 {
+			keyText = "[" + key.Content[0].Value + "]"
+		}
+		field, err := u.findField(keyText, msgDesc)
+		if err != nil {
+			continue
+		}
+		if j, ok := targets[field.FullName()]; ok {
+			copyEntryComments(key, from.Content[i+1], to.Content[j], to.Content[j+1])
+			u.copyFieldComments(from.Content[i+1], to.Content[j+1], field)
+		}
+	}
+}
+
+// copyFieldComments copies the comments of the given value of a field to its
+// formatted value.
+func (u *unmarshaler) copyFieldComments(from, to *yaml.Node, field protoreflect.FieldDescriptor) {
+	switch {
+	case field.IsList():
+		copyComments(from, to)
+		if from.Kind == yaml.SequenceNode && to.Kind == yaml.SequenceNode {
+			for i := range min(len(from.Content), len(to.Content)) {
+				u.copyValueComments(from.Content[i], to.Content[i], field)
+			}
+		}
+	case field.IsMap():
+		copyComments(from, to)
+		if from.Kind == yaml.MappingNode && to.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(from.Content); i += 2 {
+				if keyNode, valueNode, ok := findEntryByKey(to, from.Content[i].Value); ok {
+					copyEntryComments(from.Content[i], from.Content[i+1], keyNode, valueNode)
+					u.copyValueComments(from.Content[i+1], valueNode, field.MapValue())
+				}
+			}
+		}
+	default:
+		u.copyValueComments(from, to, field)
+	}
+}
+
+// copyValueComments copies the comments of a single value of the given field.
+func (u *unmarshaler) copyValueComments(from, to *yaml.Node, field protoreflect.FieldDescriptor) {
+	if msgDesc := field.Message(); msgDesc != nil {
+		u.copyMessageComments(from, to, msgDesc)
+	} else {
+		copyComments(from, to)
+	}
+}
+
+// copyNodeComments copies the comments of the given node and its content to
+// the corresponding nodes, matching mapping entries by key and sequence items
+// by index.
+func copyNodeComments(from, to *yaml.Node) {
+	copyComments(from, to)
+	switch {
+	case from.Kind == yaml.MappingNode && to.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(from.Content); i += 2 {
+			if keyNode, valueNode, ok := findEntryByKey(to, from.Content[i].Value); ok {
+				copyEntryComments(from.Content[i], from.Content[i+1], keyNode, valueNode)
+				copyNodeComments(from.Content[i+1], valueNode)
+			}
+		}
+	case from.Kind == yaml.SequenceNode && to.Kind == yaml.SequenceNode:
+		for i := range min(len(from.Content), len(to.Content)) {
+			copyNodeComments(from.Content[i], to.Content[i])
+		}
+	}
+}
+
+// copyEntryComments copies the comments of the key of a mapping entry. The
+// line comment of a value that is formatted as a block collection, such as a
+// flow sequence in the input, is moved to the key.
+func copyEntryComments(fromKey, fromValue, toKey, toValue *yaml.Node) {
+	copyComments(fromKey, toKey)
+	if isBlockCollection(toValue) && fromValue.LineComment != "" && toKey.LineComment == "" {
+		toKey.LineComment = fromValue.LineComment
+	}
+}
+
+// copyComments copies the comments of one node to another. Block collections
+// do not have line comments of their own.
+func copyComments(from, to *yaml.Node) {
+	if from.HeadComment != "" {
+		to.HeadComment = from.HeadComment
+	}
+	if from.LineComment != "" && !isBlockCollection(to) {
+		to.LineComment = from.LineComment
+	}
+	if from.FootComment != "" {
+		to.FootComment = from.FootComment
+	}
+}
+
+// isBlockCollection returns true if the given node is a mapping or sequence
+// that is not in flow style.
+func isBlockCollection(node *yaml.Node) bool {
+	return (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && node.Style&yaml.FlowStyle == 0
+}
@@ -0,0 +1,208 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		Name     string
+		Options  MarshalOptions
+		Input    string
+		Expected string
+	}{
+		{
+			Name: "canonical",
+			Input: `singleString: hello
+single_int32: 0x10
+singleNestedEnum: 2
+singleDuration: 90s
+singleTimestamp: 2023-01-01T00:00:00.000Z
+repeatedString: [a, b]
+`,
+			Expected: `singleInt32: 16
+singleString: hello
+singleDuration: 90s
+singleTimestamp: "2023-01-01T00:00:00Z"
+singleNestedEnum: BAZ
+repeatedString:
+  - a
+  - b
+`,
+		},
+		{
+			Name:    "proto names",
+			Options: MarshalOptions{UseProtoNames: true},
+			Input:   "singleInt32: 1\nstandaloneMessage:\n    bb: 2\n",
+			Expected: `single_int32: 1
+standalone_message:
+  bb: 2
+`,
+		},
+		{
+			Name: "comments",
+			Input: `# The config.
+
+standaloneMessage: # The message.
+  # The value.
+  bb: 1Ki
+# The name.
+singleString: hello # Line comment.
+repeatedInt32:
+  # First.
+  - 1
+  - 2 # Second.
+mapStringString:
+  b: y # B.
+  a: x # A.
+singleInt64: 0 # Dropped.
+`,
+			Expected: `# The config.
+
+# The name.
+singleString: hello # Line comment.
+standaloneMessage: # The message.
+  # The value.
+  bb: 1024
+repeatedInt32:
+  # First.
+  - 1
+  - 2 # Second.
+mapStringString:
+  a: x # A.
+  b: y # B.
+`,
+		},
+		{
+			Name: "well-known types",
+			Input: `singleAny:
+  value: 1s # Duration.
+  "@type": type.googleapis.com/google.protobuf.Duration
+singleStruct:
+  b: 1
+  a: [x] # List.
+`,
+			Expected: `singleAny:
+  '@type': type.googleapis.com/google.protobuf.Duration
+  value: 1s # Duration.
+singleStruct:
+  a: # List.
+    - x
+  b: 1
+`,
+		},
+		{
+			Name:     "header comment",
+			Input:    "# Header.\nsingle_string: a\n# The number.\nsingleInt32: 1\n",
+			Expected: "# Header.\n# The number.\nsingleInt32: 1\nsingleString: a\n",
+		},
+		{
+			Name:     "directive",
+			Input:    "%YAML 1.2\n---\nsingle_int32: 0x1\n",
+			Expected: "%YAML 1.2\n---\nsingleInt32: 1\n",
+		},
+		{
+			Name:     "empty",
+			Input:    "# Only a comment.\n",
+			Expected: "# Only a comment.\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			options := test.Options
+			options.Indent = 2
+			var message proto3.TestAllTypes
+			formatted, err := options.Format([]byte(test.Input), &message)
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, string(formatted))
+
+			var expected proto3.TestAllTypes
+			require.NoError(t, Unmarshal([]byte(test.Input), &expected))
+			assert.True(t, proto.Equal(&expected, &message))
+			// Formatting is idempotent.
+			again, err := options.Format(formatted, &message)
+			require.NoError(t, err)
+			assert.Equal(t, string(formatted), string(again))
+		})
+	}
+}
+
+func TestFormatDocuments(t *testing.T) {
+	t.Parallel()
+	input := "# First.\nsingle_int32: 1\n---\n# Only a comment.\n---\nsingleString: hi\nsingle_int32: 0x2\n...\n"
+	var message proto3.TestAllTypes
+	formatted, err := MarshalOptions{Indent: 2}.Format([]byte(input), &message)
+	require.NoError(t, err)
+	assert.Equal(t, "# First.\nsingleInt32: 1\n---\n# Only a comment.\n---\nsingleInt32: 2\nsingleString: hi\n", string(formatted))
+	// The message is set to the last document.
+	assert.True(t, proto.Equal(&proto3.TestAllTypes{SingleInt32: 2, SingleString: "hi"}, &message))
+
+	// Directives after the first document follow an end marker.
+	formatted, err = Format([]byte("single_int32: 1\n...\n%YAML 1.2\n---\nsingle_int32: 2\n"), &message)
+	require.NoError(t, err)
+	assert.Equal(t, "singleInt32: 1\n...\n%YAML 1.2\n---\nsingleInt32: 2\n", string(formatted))
+}
+
+func TestFormatError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		Name  string
+		Input string
+		Error string
+	}{
+		{
+			Name:  "alias",
+			Input: "values:\n  - &a {oneofStringValue: x}\n  - *a\n",
+			Error: ":2:5 cannot format anchor &a",
+		},
+		{
+			Name:  "tag",
+			Input: "values:\n  - oneofStringValue: !secret x\n",
+			Error: ":2:23 cannot format tag !secret",
+		},
+		{
+			Name:  "invalid",
+			Input: "values: 1\n",
+			Error: ":1:9 expected sequence, got scalar",
+		},
+		{
+			Name:  "second document",
+			Input: "values: []\n---\nvalues: 1\n",
+			Error: ":3:9 expected sequence, got scalar\n   3 | values: 1\n",
+		},
+		{
+			Name:  "syntax",
+			Input: "values: [\n",
+			Error: ":1:1 did not find expected node content",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Format([]byte(test.Input), &testv1.Proto2Test{})
+			require.ErrorContains(t, err, test.Error)
+		})
+	}
+}
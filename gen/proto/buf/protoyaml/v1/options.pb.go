@@ -0,0 +1,245 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: buf/protoyaml/v1/options.proto
+
+package protoyamlv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Style is a YAML presentation style.
+type Style int32
+
+const (
+	// Use the default style chosen by the YAML encoder.
+	Style_STYLE_UNSPECIFIED Style = 0
+	// Render strings that span multiple lines as literal block scalars (`|`).
+	// Single-line strings use the default style.
+	Style_STYLE_LITERAL Style = 1
+	// Render lists, maps, and messages in flow style (`[a, b]`, `{k: v}`).
+	Style_STYLE_FLOW Style = 2
+	// Render strings as double-quoted scalars.
+	Style_STYLE_DOUBLE_QUOTED Style = 3
+	// Render strings as single-quoted scalars.
+	Style_STYLE_SINGLE_QUOTED Style = 4
+)
+
+// Enum value maps for Style.
+var (
+	Style_name = map[int32]string{
+		0: "STYLE_UNSPECIFIED",
+		1: "STYLE_LITERAL",
+		2: "STYLE_FLOW",
+		3: "STYLE_DOUBLE_QUOTED",
+		4: "STYLE_SINGLE_QUOTED",
+	}
+	Style_value = map[string]int32{
+		"STYLE_UNSPECIFIED":   0,
+		"STYLE_LITERAL":       1,
+		"STYLE_FLOW":          2,
+		"STYLE_DOUBLE_QUOTED": 3,
+		"STYLE_SINGLE_QUOTED": 4,
+	}
+)
+
+func (x Style) Enum() *Style {
+	p := new(Style)
+	*p = x
+	return p
+}
+
+func (x Style) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Style) Descriptor() protoreflect.EnumDescriptor {
+	return file_buf_protoyaml_v1_options_proto_enumTypes[0].Descriptor()
+}
+
+func (Style) Type() protoreflect.EnumType {
+	return &file_buf_protoyaml_v1_options_proto_enumTypes[0]
+}
+
+func (x Style) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Style.Descriptor instead.
+func (Style) EnumDescriptor() ([]byte, []int) {
+	return file_buf_protoyaml_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+// FieldOptions describe how a field is rendered in YAML.
+type FieldOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The style used for the field's value, or for each element of a repeated
+	// or map field where the style applies to scalars.
+	Style         Style `protobuf:"varint,1,opt,name=style,proto3,enum=buf.protoyaml.v1.Style" json:"style,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldOptions) Reset() {
+	*x = FieldOptions{}
+	mi := &file_buf_protoyaml_v1_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldOptions) ProtoMessage() {}
+
+func (x *FieldOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_protoyaml_v1_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldOptions.ProtoReflect.Descriptor instead.
+func (*FieldOptions) Descriptor() ([]byte, []int) {
+	return file_buf_protoyaml_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FieldOptions) GetStyle() Style {
+	if x != nil {
+		return x.Style
+	}
+	return Style_STYLE_UNSPECIFIED
+}
+
+var file_buf_protoyaml_v1_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldOptions)(nil),
+		Field:         1170,
+		Name:          "buf.protoyaml.v1.field",
+		Tag:           "bytes,1170,opt,name=field",
+		Filename:      "buf/protoyaml/v1/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Controls how protoyaml formats the field when marshaling to YAML.
+	//
+	//   message Config {
+	//     string script = 1 [(buf.protoyaml.v1.field).style = STYLE_LITERAL];
+	//     repeated string tags = 2 [(buf.protoyaml.v1.field).style = STYLE_FLOW];
+	//   }
+	//
+	// The option only affects presentation: the output parses to the same
+	// message whether or not the reader understands the option.
+	//
+	// optional buf.protoyaml.v1.FieldOptions field = 1170;
+	E_Field = &file_buf_protoyaml_v1_options_proto_extTypes[0]
+)
+
+var File_buf_protoyaml_v1_options_proto protoreflect.FileDescriptor
+
+const file_buf_protoyaml_v1_options_proto_rawDesc = "" +
+	"\n" +
+	"\x1ebuf/protoyaml/v1/options.proto\x12\x10buf.protoyaml.v1\x1a google/protobuf/descriptor.proto\"=\n" +
+	"\fFieldOptions\x12-\n" +
+	"\x05style\x18\x01 \x01(\x0e2\x17.buf.protoyaml.v1.StyleR\x05style*s\n" +
+	"\x05Style\x12\x15\n" +
+	"\x11STYLE_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rSTYLE_LITERAL\x10\x01\x12\x0e\n" +
+	"\n" +
+	"STYLE_FLOW\x10\x02\x12\x17\n" +
+	"\x13STYLE_DOUBLE_QUOTED\x10\x03\x12\x17\n" +
+	"\x13STYLE_SINGLE_QUOTED\x10\x04:T\n" +
+	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\x92\t \x01(\v2\x1e.buf.protoyaml.v1.FieldOptionsR\x05fieldB?Z=buf.build/go/protoyaml/gen/proto/buf/protoyaml/v1;protoyamlv1b\x06proto3"
+
+var (
+	file_buf_protoyaml_v1_options_proto_rawDescOnce sync.Once
+	file_buf_protoyaml_v1_options_proto_rawDescData []byte
+)
+
+func file_buf_protoyaml_v1_options_proto_rawDescGZIP() []byte {
+	file_buf_protoyaml_v1_options_proto_rawDescOnce.Do(func() {
+		file_buf_protoyaml_v1_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_buf_protoyaml_v1_options_proto_rawDesc), len(file_buf_protoyaml_v1_options_proto_rawDesc)))
+	})
+	return file_buf_protoyaml_v1_options_proto_rawDescData
+}
+
+var file_buf_protoyaml_v1_options_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_buf_protoyaml_v1_options_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_buf_protoyaml_v1_options_proto_goTypes = []any{
+	(Style)(0),                        // 0: buf.protoyaml.v1.Style
+	(*FieldOptions)(nil),              // 1: buf.protoyaml.v1.FieldOptions
+	(*descriptorpb.FieldOptions)(nil), // 2: google.protobuf.FieldOptions
+}
+var file_buf_protoyaml_v1_options_proto_depIdxs = []int32{
+	0, // 0: buf.protoyaml.v1.FieldOptions.style:type_name -> buf.protoyaml.v1.Style
+	2, // 1: buf.protoyaml.v1.field:extendee -> google.protobuf.FieldOptions
+	1, // 2: buf.protoyaml.v1.field:type_name -> buf.protoyaml.v1.FieldOptions
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	2, // [2:3] is the sub-list for extension type_name
+	1, // [1:2] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_buf_protoyaml_v1_options_proto_init() }
+func file_buf_protoyaml_v1_options_proto_init() {
+	if File_buf_protoyaml_v1_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_buf_protoyaml_v1_options_proto_rawDesc), len(file_buf_protoyaml_v1_options_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_buf_protoyaml_v1_options_proto_goTypes,
+		DependencyIndexes: file_buf_protoyaml_v1_options_proto_depIdxs,
+		EnumInfos:         file_buf_protoyaml_v1_options_proto_enumTypes,
+		MessageInfos:      file_buf_protoyaml_v1_options_proto_msgTypes,
+		ExtensionInfos:    file_buf_protoyaml_v1_options_proto_extTypes,
+	}.Build()
+	File_buf_protoyaml_v1_options_proto = out.File
+	file_buf_protoyaml_v1_options_proto_goTypes = nil
+	file_buf_protoyaml_v1_options_proto_depIdxs = nil
+}
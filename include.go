@@ -0,0 +1,252 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const includeTag = "!include"
+
+// DefaultMaxIncludeBytes is the maximum total size of the files included by a
+// document, in bytes, if UnmarshalOptions.MaxDocumentBytes is 0.
+const DefaultMaxIncludeBytes = 64 << 20
+
+// sourceFile is a file included with an `!include` tag.
+type sourceFile struct {
+	// The name of the file in UnmarshalOptions.IncludeFS.
+	name string
+	// The path of the file, as reported in errors.
+	path  string
+	lines []string
+	// The include tag that included the file.
+	site *yaml.Node
+	// The file that contains the include tag, or nil for the input itself.
+	parent *sourceFile
+}
+
+// resolveIncludes returns the given node with all `!include` tags replaced by
+// the content of the included files.
+//
+// An `!include path` value is replaced by the included document. An
+// `!include path` key with a null value merges the entries of the included
+// mapping into the containing mapping, as a `<<` merge key would.
+func (u *unmarshaler) resolveIncludes(node *yaml.Node, file *sourceFile) *yaml.Node {
+	switch {
+	case node.Kind == yaml.AliasNode && node.Alias != nil:
+		target := u.resolveIncludedAnchor(node.Alias, file)
+		if target == node.Alias {
+			return node
+		}
+		result := u.copyNode(node)
+		result.Alias = target
+		return result
+	case node.Anchor != "":
+		return u.resolveIncludedAnchor(node, file)
+	default:
+		return u.resolveIncludedContent(node, file)
+	}
+}
+
+// resolveIncludedAnchor resolves the includes in the given anchored node, at
+// most once.
+func (u *unmarshaler) resolveIncludedAnchor(node *yaml.Node, file *sourceFile) *yaml.Node {
+	if resolved, ok := u.includedAnchors[node]; ok {
+		return resolved
+	}
+	if u.includedAnchors == nil {
+		u.includedAnchors = make(map[*yaml.Node]*yaml.Node)
+	}
+	u.includedAnchors[node] = node // Aliases within the anchor refer to itself.
+	resolved := u.resolveIncludedContent(node, file)
+	u.includedAnchors[node] = resolved
+	return resolved
+}
+
+// resolveIncludedContent resolves the given include tag, or the includes in the
+// content of the given node.
+func (u *unmarshaler) resolveIncludedContent(node *yaml.Node, file *sourceFile) *yaml.Node {
+	if node.Kind == yaml.ScalarNode && node.Tag == includeTag {
+		return u.include(node, file)
+	}
+	content := make([]*yaml.Node, len(node.Content))
+	changed := false
+	for i := 0; i < len(node.Content); i++ {
+		if node.Kind == yaml.MappingNode && i+1 < len(node.Content) && isIncludeKey(node.Content[i]) {
+			content[i], content[i+1] = u.includeEntry(node.Content[i], node.Content[i+1], file)
+			i++
+			changed = true
+			continue
+		}
+		content[i] = u.resolveIncludes(node.Content[i], file)
+		changed = changed || content[i] != node.Content[i]
+	}
+	if !changed {
+		return node
+	}
+	result := u.copyNode(node)
+	result.Content = content
+	return result
+}
+
+// includeEntry returns the merge key and included mapping that replace the given
+// include key and its null value.
+func (u *unmarshaler) includeEntry(keyNode *yaml.Node, valueNode *yaml.Node, file *sourceFile) (*yaml.Node, *yaml.Node) {
+	if !isNull(valueNode) {
+		u.addErrorf(valueNode, "expected null value for %s key, got %v", includeTag, getNodeKind(valueNode.Kind))
+		return keyNode, valueNode
+	}
+	mergeKey := u.copyNode(keyNode)
+	mergeKey.Tag = mergeTag
+	mergeKey.Value = "<<"
+	included := u.include(keyNode, file)
+	if isNull(included) {
+		// Nothing to merge.
+		included = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: included.Line, Column: included.Column}
+	}
+	return mergeKey, included
+}
+
+// include returns the root node of the file included by the given tag.
+func (u *unmarshaler) include(tag *yaml.Node, file *sourceFile) *yaml.Node {
+	dir := "."
+	if file != nil {
+		dir = path.Dir(file.name)
+	}
+	name := path.Join(dir, tag.Value)
+	chain := []string{name}
+	for parent := file; parent != nil; parent = parent.parent {
+		chain = append(chain, parent.name)
+		if parent.name == name {
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			u.addErrorf(tag, "include cycle: %s", strings.Join(chain, " -> "))
+			return tag
+		}
+	}
+	maxBytes := u.options.MaxDocumentBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxIncludeBytes
+	}
+	if u.includedBytes > maxBytes || (u.options.MaxNodes > 0 && u.includedNodes > u.options.MaxNodes) {
+		return tag // The limit was already reported.
+	}
+	data, err := fs.ReadFile(u.options.IncludeFS, name)
+	if err != nil {
+		u.addErrorf(tag, "failed to include %#v: %w", tag.Value, err)
+		return tag
+	}
+	if u.options.MaxDocumentBytes > 0 && len(data) > u.options.MaxDocumentBytes {
+		u.addErrorf(tag, "failed to include %#v: exceeded maximum document size of %d bytes", tag.Value, u.options.MaxDocumentBytes)
+		return tag
+	}
+	// Each include is expanded separately, so the limits apply to the total.
+	u.includedBytes += len(data)
+	if u.includedBytes > maxBytes {
+		u.addErrorf(tag, "failed to include %#v: exceeded maximum size of %d bytes for all included files", tag.Value, maxBytes)
+		return tag
+	}
+	included := &sourceFile{
+		name:   name,
+		path:   path.Join(path.Dir(u.options.Path), name),
+		lines:  strings.Split(string(data), "\n"),
+		site:   tag,
+		parent: file,
+	}
+	var document yaml.Node
+	if err := parseYAML(data, &document); err != nil {
+		options := u.options
+		options.Path = included.path
+		var errList ErrorList
+		if errors.As(options.newSyntaxError(err, data, 0), &errList) {
+			for _, syntaxErr := range errList {
+				syntaxErr.includes = u.includeSites(included)
+				u.errors = append(u.errors, syntaxErr)
+			}
+		}
+		return tag
+	}
+	if len(document.Content) != 1 {
+		// An empty file is null.
+		root := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: tag.Line, Column: tag.Column}
+		u.addSourceFile(root, file)
+		return root
+	}
+	root := document.Content[0]
+	u.includedNodes += countNodes(root)
+	if u.options.MaxNodes > 0 && u.includedNodes > u.options.MaxNodes {
+		u.addErrorf(tag, "failed to include %#v: exceeded maximum of %d nodes for all included files", tag.Value, u.options.MaxNodes)
+		return tag
+	}
+	u.addSourceFile(root, included)
+	return u.resolveIncludes(root, included)
+}
+
+// addSourceFile records that the given node and its descendants are from the
+// given file.
+func (u *unmarshaler) addSourceFile(node *yaml.Node, file *sourceFile) {
+	if u.sourceFiles == nil {
+		u.sourceFiles = make(map[*yaml.Node]*sourceFile)
+	}
+	if _, ok := u.sourceFiles[node]; ok {
+		return // Already visited through an alias.
+	}
+	u.sourceFiles[node] = file
+	for _, child := range node.Content {
+		u.addSourceFile(child, file)
+	}
+	if node.Alias != nil {
+		u.addSourceFile(node.Alias, file)
+	}
+}
+
+// line returns the source text of the given 1-based line number, or an empty
+// string if the line is not known.
+func (f *sourceFile) line(line int) string {
+	if line < 1 || line > len(f.lines) {
+		return ""
+	}
+	return f.lines[line-1]
+}
+
+// includeSites returns the include tags that the given file was included from,
+// innermost first.
+func (u *unmarshaler) includeSites(file *sourceFile) []*sourceSite {
+	var sites []*sourceSite
+	for ; file != nil; file = file.parent {
+		sites = append(sites, u.newSourceSite(file.site))
+	}
+	return sites
+}
+
+// countNodes returns the number of nodes in the given tree, without expanding
+// aliases.
+func countNodes(node *yaml.Node) int {
+	count := 1
+	for _, child := range node.Content {
+		count += countNodes(child)
+	}
+	return count
+}
+
+func isIncludeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == includeTag
+}
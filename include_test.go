@@ -0,0 +1,154 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+	"testing/fstest"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+var testIncludeFS = fstest.MapFS{
+	"values.yaml":         {Data: []byte("- single_int32: 1\n- !include nested/value.yaml\n")},
+	"nested/value.yaml":   {Data: []byte("single_string: hi\nsingle_nested_message: !include message.yaml\n")},
+	"nested/message.yaml": {Data: []byte("bb: 2\n")},
+	"base.yaml":           {Data: []byte("single_int32: 1\nsingle_string: base\n")},
+	"empty.yaml":          {Data: []byte("# Nothing here.\n")},
+	"bad.yaml":            {Data: []byte("single_int32: 1\nsingle_bool: 1\n")},
+	"nested/bad.yaml":     {Data: []byte("values:\n  - !include ../bad.yaml\n")},
+	"syntax.yaml":         {Data: []byte("single_int32: [1\n")},
+	"cycle/a.yaml":        {Data: []byte("single_nested_message: !include b.yaml\n")},
+	"cycle/b.yaml":        {Data: []byte("bb: !include a.yaml\n")},
+}
+
+func TestInclude(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{IncludeFS: testIncludeFS}
+	actual := &testv1.Proto3Test{}
+	require.NoError(t, options.Unmarshal([]byte("values: !include values.yaml\n"), actual))
+	expected := &testv1.Proto3Test{Values: []*proto3.TestAllTypes{
+		{SingleInt32: 1},
+		{SingleString: "hi", NestedType: &proto3.TestAllTypes_SingleNestedMessage{SingleNestedMessage: &proto3.TestAllTypes_NestedMessage{Bb: 2}}},
+	}}
+	assert.True(t, proto.Equal(expected, actual), "expected %v, got %v", expected, actual)
+
+	// Include keys merge the included mapping, and explicit keys take precedence.
+	actual = &testv1.Proto3Test{}
+	data := []byte("values:\n  - !include base.yaml:\n    single_string: override\n  - !include empty.yaml: ~\n")
+	require.NoError(t, options.Unmarshal(data, actual))
+	require.Len(t, actual.GetValues(), 2)
+	assert.Equal(t, int32(1), actual.GetValues()[0].GetSingleInt32())
+	assert.Equal(t, "override", actual.GetValues()[0].GetSingleString())
+
+	// An empty file is null, and aliases see the included content.
+	actual = &testv1.Proto3Test{}
+	data = []byte("values:\n  - single_nested_message: !include empty.yaml\n  - &base !include base.yaml\n  - *base\n")
+	require.NoError(t, options.Unmarshal(data, actual))
+	require.Len(t, actual.GetValues(), 3)
+	assert.Equal(t, "base", actual.GetValues()[2].GetSingleString())
+}
+
+func TestIncludeErrors(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Data     string
+		Expected string
+	}{
+		{
+			Data: "values: [!include missing.yaml]\n",
+			Expected: `dir/test.yaml:1:10 failed to include "missing.yaml": open missing.yaml: file does not exist
+   1 | values: [!include missing.yaml]
+   1 | .........^
+`,
+		},
+		{
+			Data: "!include nested/bad.yaml:\n",
+			Expected: `dir/bad.yaml:2:14 expected bool, got "1"
+   2 | single_bool: 1
+   2 | .............^
+dir/nested/bad.yaml:2:5 in inclusion of ../bad.yaml
+   2 |   - !include ../bad.yaml
+   2 | ....^
+dir/test.yaml:1:1 in inclusion of nested/bad.yaml
+   1 | !include nested/bad.yaml:
+   1 | ^
+`,
+		},
+		{
+			Data: "values: [!include syntax.yaml]\n",
+			Expected: `dir/syntax.yaml:1:1 did not find expected ',' or ']'
+   1 | single_int32: [1
+   1 | ^
+dir/test.yaml:1:10 in inclusion of syntax.yaml
+   1 | values: [!include syntax.yaml]
+   1 | .........^
+`,
+		},
+		{
+			Data: "values: [!include cycle/a.yaml]\n",
+			Expected: `dir/cycle/b.yaml:1:5 include cycle: cycle/a.yaml -> cycle/b.yaml -> cycle/a.yaml
+   1 | bb: !include a.yaml
+   1 | ....^
+dir/cycle/a.yaml:1:24 in inclusion of b.yaml
+   1 | single_nested_message: !include b.yaml
+   1 | .......................^
+dir/test.yaml:1:10 in inclusion of cycle/a.yaml
+   1 | values: [!include cycle/a.yaml]
+   1 | .........^
+`,
+		},
+		{
+			Data: "!include base.yaml: {}\n",
+			Expected: `dir/test.yaml:1:21 expected null value for !include key, got mapping
+   1 | !include base.yaml: {}
+   1 | ....................^
+`,
+		},
+	} {
+		options := UnmarshalOptions{Path: "dir/test.yaml", IncludeFS: testIncludeFS}
+		err := options.Unmarshal([]byte(testCase.Data), &testv1.Proto3Test{})
+		require.Error(t, err, testCase.Data)
+		assert.Equal(t, testCase.Expected, err.Error())
+	}
+}
+
+func TestIncludeDisabled(t *testing.T) {
+	t.Parallel()
+	err := Unmarshal([]byte("values: !include values.yaml\n"), &testv1.Proto3Test{})
+	require.Error(t, err)
+}
+
+func TestIncludeLimits(t *testing.T) {
+	t.Parallel()
+	// The limits apply to the total of all included files, not to each file.
+	data := []byte("values: [!include base.yaml, !include base.yaml, !include base.yaml]\n")
+	options := UnmarshalOptions{IncludeFS: testIncludeFS, MaxDocumentBytes: 100}
+	err := options.Unmarshal(data, &testv1.Proto3Test{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `1:50 failed to include "base.yaml": exceeded maximum size of 100 bytes for all included files`)
+
+	options = UnmarshalOptions{IncludeFS: testIncludeFS, MaxNodes: 12}
+	err = options.Unmarshal(data, &testv1.Proto3Test{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `1:50 failed to include "base.yaml": exceeded maximum of 12 nodes for all included files`)
+
+	options = UnmarshalOptions{IncludeFS: testIncludeFS, MaxDocumentBytes: 200, MaxNodes: 100}
+	require.NoError(t, options.Unmarshal(data, &testv1.Proto3Test{}))
+}
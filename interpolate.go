@@ -0,0 +1,180 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// variableError is an error in a variable reference, at the given byte offset
+// in a scalar value.
+type variableError struct {
+	offset int
+	token  string
+	err    error
+}
+
+// interpolate returns the given node with the variable references in scalar
+// values replaced by the values given by UnmarshalOptions.LookupVariable.
+//
+// Mapping keys are not interpolated.
+func (u *unmarshaler) interpolate(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.ScalarNode {
+		return u.interpolateScalar(node)
+	}
+	var content []*yaml.Node
+	for i, child := range node.Content {
+		resolved := child
+		if node.Kind != yaml.MappingNode || i%2 == 1 {
+			resolved = u.interpolate(child)
+		}
+		if resolved != child && content == nil {
+			content = make([]*yaml.Node, len(node.Content))
+			copy(content, node.Content[:i])
+		}
+		if content != nil {
+			content[i] = resolved
+		}
+	}
+	if content == nil {
+		return node
+	}
+	result := u.copyNode(node)
+	result.Content = content
+	return result
+}
+
+func (u *unmarshaler) interpolateScalar(node *yaml.Node) *yaml.Node {
+	if !strings.Contains(node.Value, "$") {
+		return node
+	}
+	value, errs := expandVariables(node.Value, u.options.LookupVariable)
+	for _, err := range errs {
+		u.addError(u.findVariableToken(node, err.offset, err.token), err.err)
+	}
+	if len(errs) > 0 || value == node.Value {
+		return node
+	}
+	result := u.copyNode(node)
+	result.Value = value
+	if node.Style&(yaml.TaggedStyle|yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		// Resolve the tag of the plain scalar, as if the value had been written.
+		result.Tag = ""
+		result.Tag = result.ShortTag()
+	}
+	return result
+}
+
+// findVariableToken returns a node that spans the given token, which is at the
+// given byte offset in the value of the given scalar node.
+//
+// The token is located in the source, in case the scalar is quoted or spans
+// multiple lines. If it cannot be found, returns the scalar node itself.
+func (u *unmarshaler) findVariableToken(node *yaml.Node, offset int, token string) *yaml.Node {
+	// The number of identical tokens that precede this one.
+	skip := strings.Count(node.Value[:offset], token)
+	lastLine := node.Line + strings.Count(node.Value, "\n") + 1
+	for lineNum := node.Line; lineNum <= lastLine; lineNum++ {
+		line := u.nodeSourceLine(node, lineNum)
+		start := 0
+		if lineNum == node.Line {
+			start = len(string([]rune(line)[:min(max(node.Column-1, 0), len([]rune(line)))]))
+		}
+		for {
+			index := strings.Index(line[start:], token)
+			if index < 0 {
+				break
+			}
+			start += index
+			if skip == 0 {
+				result := u.copyNode(node)
+				result.Line = lineNum
+				result.Column = len([]rune(line[:start])) + 1
+				result.Value = token
+				result.Tag = "!!str"
+				result.Style = 0
+				return result
+			}
+			skip--
+			start += len(token)
+		}
+	}
+	return node
+}
+
+// expandVariables replaces the `${NAME}` and `${NAME:-default}` references in
+// the given value with the values returned by lookup, and `$$` with `$`.
+func expandVariables(value string, lookup func(name string) (string, bool)) (string, []*variableError) {
+	var result strings.Builder
+	var errs []*variableError
+	for i := 0; i < len(value); {
+		if value[i] != '$' || i+1 == len(value) {
+			result.WriteByte(value[i])
+			i++
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			result.WriteByte('$')
+			i += 2
+		case '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				errs = append(errs, &variableError{offset: i, token: value[i:], err: errors.New("unterminated variable reference")})
+				result.WriteString(value[i:])
+				i = len(value)
+				break
+			}
+			token := value[i : i+end+1]
+			i += len(token)
+			name, defaultValue, hasDefault := strings.Cut(token[2:len(token)-1], ":-")
+			if !isVariableName(name) {
+				errs = append(errs, &variableError{offset: i - len(token), token: token, err: fmt.Errorf("invalid variable name %#v", name)})
+				continue
+			}
+			variable, ok := lookup(name)
+			switch {
+			case ok && (variable != "" || !hasDefault):
+				result.WriteString(variable)
+			case hasDefault:
+				result.WriteString(defaultValue)
+			default:
+				errs = append(errs, &variableError{offset: i - len(token), token: token, err: fmt.Errorf("variable %s is not set", name)})
+			}
+		default:
+			result.WriteByte('$')
+			i++
+		}
+	}
+	return result.String(), errs
+}
+
+// isVariableName returns true if the given name is a valid variable name: a
+// letter or underscore, followed by letters, digits or underscores.
+func isVariableName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,108 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+	"time"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func testLookupVariable(name string) (string, bool) {
+	value, ok := map[string]string{
+		"REGION":  "us-east-1",
+		"PORT":    "443",
+		"ENABLED": "true",
+		"TIMEOUT": "1m30s",
+		"EMPTY":   "",
+	}[name]
+	return value, ok
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{LookupVariable: testLookupVariable}
+	data := []byte(`values:
+  - single_string: ${REGION}
+    single_int32: ${PORT}
+    single_int64: ${MISSING:-8080}
+    single_bool: ${ENABLED}
+    single_duration: ${TIMEOUT}
+    repeated_string:
+      - "${REGION}-${EMPTY:-default}"
+      - 'cost: $$5, $${REGION}, $5'
+      - ${EMPTY}
+    map_string_string:
+      ${REGION}: value
+`)
+	actual := &testv1.Proto3Test{}
+	require.NoError(t, options.Unmarshal(data, actual))
+	value := actual.GetValues()[0]
+	assert.Equal(t, "us-east-1", value.GetSingleString())
+	assert.Equal(t, int32(443), value.GetSingleInt32())
+	assert.Equal(t, int64(8080), value.GetSingleInt64())
+	assert.True(t, value.GetSingleBool())
+	assert.Equal(t, 90*time.Second, value.GetSingleDuration().AsDuration())
+	assert.Equal(t, []string{"us-east-1-default", "cost: $5, ${REGION}, $5", ""}, value.GetRepeatedString())
+	assert.Equal(t, map[string]string{"${REGION}": "value"}, value.GetMapStringString())
+
+	// Plain scalars are resolved as if the value had been written.
+	structValue := &structpb.Value{}
+	require.NoError(t, options.Unmarshal([]byte("- ${ENABLED}\n- '${ENABLED}'\n- ${PORT}\n- ${EMPTY}\n"), structValue))
+	assert.Equal(t, []any{true, "true", float64(443), nil}, structValue.AsInterface())
+
+	// Values are not interpolated by default.
+	actual = &testv1.Proto3Test{}
+	require.NoError(t, Unmarshal([]byte("values:\n  - single_string: ${REGION}\n"), actual))
+	assert.Equal(t, "${REGION}", actual.GetValues()[0].GetSingleString())
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{Path: "test.yaml", LookupVariable: testLookupVariable}
+	data := []byte(`values:
+  - single_string: "${REGION}/${MISSING}"
+  - single_string: ${1NVALID} ${REGION
+  - repeated_string:
+    - |
+      ${MISSING}
+      ${MISSING}
+`)
+	err := options.Unmarshal(data, &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 5)
+	for _, testCase := range []struct {
+		Line, Column, EndColumn int
+		Message                 string
+	}{
+		{Line: 2, Column: 31, EndColumn: 41, Message: "variable MISSING is not set"},
+		{Line: 3, Column: 20, EndColumn: 30, Message: `invalid variable name "1NVALID"`},
+		{Line: 3, Column: 31, EndColumn: 39, Message: "unterminated variable reference"},
+		{Line: 6, Column: 7, EndColumn: 17, Message: "variable MISSING is not set"},
+		{Line: 7, Column: 7, EndColumn: 17, Message: "variable MISSING is not set"},
+	} {
+		actual := errList[0]
+		errList = errList[1:]
+		assert.Equal(t, testCase.Line, actual.Line)
+		assert.Equal(t, testCase.Column, actual.Column)
+		assert.Equal(t, testCase.EndColumn, actual.EndColumn)
+		assert.Equal(t, testCase.Message, actual.Cause.Error())
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonschema generates JSON Schemas for the YAML representation of
+// Protobuf messages, for use by editor tooling such as yaml-language-server.
+//
+// The schemas describe the documents accepted by protoyaml.UnmarshalOptions:
+// fields may use their JSON or proto names or their numbers, enums their names
+// or numbers, integers may be written as strings with byte units such as "1Ki",
+// and well-known types use their special representations. Custom tags,
+// includes and variable references are not described.
+package jsonschema
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// SchemaVersion is the JSON Schema draft of the generated schemas.
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// Patterns of the string representations of scalar values.
+const (
+	// A number that may have an integer value, such as 1.0 or 1.5e3.
+	floatIntPattern = `[0-9]+(\.0*)?|([0-9]+(\.[0-9]*)?|\.[0-9]+)[eE][-+]?[0-9]+`
+	// A decimal, hexadecimal, octal or binary integer.
+	intLiteralPattern = `0[xX][0-9a-fA-F]+|0[oO][0-7]+|0[bB][01]+|` + floatIntPattern
+	// A number of bytes with a unit, such as 1.5Ki.
+	byteCountPattern = `([0-9]+(\.[0-9]*)?|\.[0-9]+)(k|M|G|T|P|E|Ki|Mi|Gi|Ti|Pi|Ei)`
+
+	signedPattern   = `^[-+]?(` + intLiteralPattern + `|` + byteCountPattern + `)$`
+	unsignedPattern = `^\+?(` + intLiteralPattern + `|` + byteCountPattern + `)$`
+	enumPattern     = `^[-+]?(` + intLiteralPattern + `)$`
+	floatPattern    = `^[-+]?(([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?|[iI][nN][fF]([iI][nN][iI][tT][yY])?|[nN][aA][nN])$`
+	base64Pattern   = `^[A-Za-z0-9+/_-]*={0,2}$`
+	durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(h|m|s|ms|us|µs|μs|ns))+)$`
+)
+
+// Generate returns the JSON Schema for YAML documents of the given message.
+func Generate(desc protoreflect.MessageDescriptor) ([]byte, error) {
+	return Options{}.Generate(desc)
+}
+
+// Options configure the generated JSON Schema. They correspond to the
+// options of the same name in protoyaml.UnmarshalOptions.
+type Options struct {
+	// Resolver is used to find the extensions of each message, which may be
+	// set with `[full.name]` keys. If nil, protoregistry.GlobalTypes is used.
+	Resolver interface {
+		RangeExtensionsByMessage(message protoreflect.FullName, f func(protoreflect.ExtensionType) bool)
+	}
+
+	// If AllowPartial is set, required fields may be omitted.
+	AllowPartial bool
+
+	// If DiscardUnknown is set, unknown fields are allowed.
+	DiscardUnknown bool
+}
+
+// Generate returns the JSON Schema for YAML documents of the given message.
+func (o Options) Generate(desc protoreflect.MessageDescriptor) ([]byte, error) {
+	gen := &generator{options: o, definitions: make(map[string]any)}
+	root := gen.messageRef(desc)
+	return json.MarshalIndent(map[string]any{
+		"$schema":     SchemaVersion,
+		"allOf":       []any{root},
+		"definitions": gen.definitions,
+	}, "", "  ")
+}
+
+type schema = map[string]any
+
+type generator struct {
+	options     Options
+	definitions map[string]any
+}
+
+// messageRef returns a reference to the definition of the given message,
+// adding it if needed.
+func (g *generator) messageRef(desc protoreflect.MessageDescriptor) schema {
+	name := string(desc.FullName())
+	if _, ok := g.definitions[name]; !ok {
+		g.definitions[name] = schema{} // Placeholder for recursive messages.
+		g.definitions[name] = g.messageSchema(desc)
+	}
+	return schema{"$ref": "#/definitions/" + name}
+}
+
+// enumRef returns a reference to the definition of the given enum, adding it
+// if needed.
+func (g *generator) enumRef(desc protoreflect.EnumDescriptor) schema {
+	name := string(desc.FullName())
+	if _, ok := g.definitions[name]; !ok {
+		g.definitions[name] = enumSchema(desc)
+	}
+	return schema{"$ref": "#/definitions/" + name}
+}
+
+// messageSchema returns the schema of the given message, including the
+// special representations of well-known types.
+func (g *generator) messageSchema(desc protoreflect.MessageDescriptor) schema {
+	switch desc.FullName() {
+	case "google.protobuf.Any":
+		return schema{"anyOf": []any{
+			schema{
+				"type":       "object",
+				"properties": schema{"@type": schema{"type": "string"}},
+				"required":   []string{"@type"},
+			},
+			schema{"type": "object", "maxProperties": 0},
+			schema{"type": "null"},
+		}}
+	case "google.protobuf.Duration":
+		return schema{"anyOf": []any{
+			schema{"type": "string", "pattern": durationPattern},
+			schema{"const": 0},
+			g.objectSchema(desc),
+		}}
+	case "google.protobuf.Timestamp":
+		return schema{"anyOf": []any{
+			schema{"type": "string", "format": "date-time"},
+			g.objectSchema(desc),
+		}}
+	case "google.protobuf.BoolValue", "google.protobuf.BytesValue",
+		"google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int32Value", "google.protobuf.Int64Value",
+		"google.protobuf.UInt32Value", "google.protobuf.UInt64Value",
+		"google.protobuf.StringValue":
+		if value := desc.Fields().ByName("value"); value != nil {
+			return schema{"anyOf": []any{g.singularSchema(value), g.objectSchema(desc)}}
+		}
+	case "google.protobuf.Value":
+		return schema{}
+	case "google.protobuf.ListValue":
+		return schema{"anyOf": []any{schema{"type": "array"}, g.objectSchema(desc)}}
+	case "google.protobuf.Struct":
+		return schema{"type": []string{"object", "null"}}
+	}
+	return g.objectSchema(desc)
+}
+
+// objectSchema returns the schema of the given message as a mapping of its
+// fields.
+func (g *generator) objectSchema(desc protoreflect.MessageDescriptor) schema {
+	properties := schema{}
+	var constraints []any
+	addField := func(field protoreflect.FieldDescriptor) []string {
+		names := getFieldKeys(field)
+		fieldSchema := withDescription(g.fieldSchema(field), getComment(field))
+		for _, name := range names {
+			properties[name] = fieldSchema
+		}
+		if field.Cardinality() == protoreflect.Required && !g.options.AllowPartial {
+			constraints = append(constraints, requireAny(names))
+		}
+		return names
+	}
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		addField(fields.Get(i))
+	}
+	g.rangeExtensions(desc, func(ext protoreflect.ExtensionTypeDescriptor) {
+		addField(ext)
+	})
+	oneofs := desc.Oneofs()
+	for i := range oneofs.Len() {
+		if oneof := oneofs.Get(i); !oneof.IsSynthetic() && oneof.Fields().Len() > 1 {
+			var keys []string
+			for j := range oneof.Fields().Len() {
+				keys = append(keys, getFieldKeys(oneof.Fields().Get(j))...)
+			}
+			constraints = append(constraints, atMostOne(keys))
+		}
+	}
+
+	result := withDescription(schema{"properties": properties}, getComment(desc))
+	// Null is accepted for messages, unless a required field must be set.
+	result["type"] = []string{"object", "null"}
+	for i := range fields.Len() {
+		if fields.Get(i).Cardinality() == protoreflect.Required && !g.options.AllowPartial {
+			result["type"] = "object"
+			break
+		}
+	}
+	if !g.options.DiscardUnknown {
+		result["additionalProperties"] = false
+	}
+	if len(constraints) > 0 {
+		result["allOf"] = constraints
+	}
+	return result
+}
+
+// rangeExtensions calls f for each extension of the given message known to
+// the resolver, ordered by full name.
+func (g *generator) rangeExtensions(desc protoreflect.MessageDescriptor, f func(protoreflect.ExtensionTypeDescriptor)) {
+	if desc.ExtensionRanges().Len() == 0 {
+		return
+	}
+	resolver := g.options.Resolver
+	if resolver == nil {
+		resolver = protoregistry.GlobalTypes
+	}
+	var exts []protoreflect.ExtensionTypeDescriptor
+	resolver.RangeExtensionsByMessage(desc.FullName(), func(ext protoreflect.ExtensionType) bool {
+		exts = append(exts, ext.TypeDescriptor())
+		return true
+	})
+	sort.Slice(exts, func(i, j int) bool {
+		return exts[i].FullName() < exts[j].FullName()
+	})
+	for _, ext := range exts {
+		f(ext)
+	}
+}
+
+func (g *generator) fieldSchema(field protoreflect.FieldDescriptor) schema {
+	switch {
+	case field.IsList():
+		return schema{"type": "array", "items": g.singularSchema(field)}
+	case field.IsMap():
+		return schema{
+			"type":                 "object",
+			"propertyNames":        mapKeySchema(field.MapKey()),
+			"additionalProperties": g.singularSchema(field.MapValue()),
+		}
+	default:
+		return g.singularSchema(field)
+	}
+}
+
+// singularSchema returns the schema of a single value of the given field.
+func (g *generator) singularSchema(field protoreflect.FieldDescriptor) schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return schema{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return integerSchema(-1<<31, 1<<31-1, signedPattern)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return integerSchema(-1<<63, 1<<63-1, signedPattern)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return integerSchema(0, 1<<32-1, unsignedPattern)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return integerSchema(0, 1<<64-1, unsignedPattern)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return schema{"anyOf": []any{
+			schema{"type": "number"},
+			schema{"type": "string", "pattern": floatPattern},
+		}}
+	case protoreflect.StringKind:
+		// Any scalar is accepted as its text.
+		return schema{"type": []string{"string", "number", "boolean", "null"}}
+	case protoreflect.BytesKind:
+		return schema{"type": "string", "contentEncoding": "base64", "pattern": base64Pattern}
+	case protoreflect.EnumKind:
+		if field.Enum().FullName() == "google.protobuf.NullValue" {
+			// Any scalar is accepted as null.
+			return schema{"type": []string{"null", "string", "number", "boolean"}}
+		}
+		return g.enumRef(field.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.messageRef(field.Message())
+	default:
+		return schema{}
+	}
+}
+
+// enumSchema returns the schema of the given enum, accepting value names and
+// numbers.
+func enumSchema(desc protoreflect.EnumDescriptor) schema {
+	values := desc.Values()
+	names := make([]string, 0, values.Len())
+	for i := range values.Len() {
+		names = append(names, string(values.Get(i).Name()))
+	}
+	anyOf := append([]any{schema{"enum": names}}, integerSchemas(-1<<31, 1<<31-1, enumPattern)...)
+	return withDescription(schema{"anyOf": anyOf}, getComment(desc))
+}
+
+// mapKeySchema returns the schema of the given map key field, as the text of
+// a YAML mapping key.
+func mapKeySchema(field protoreflect.FieldDescriptor) schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return schema{"enum": []string{"true", "false"}}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return schema{"pattern": signedPattern}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return schema{"pattern": unsignedPattern}
+	default:
+		return schema{}
+	}
+}
+
+// integerSchema returns the schema of an integer in the given range, which may
+// also be written as a string matching the given pattern.
+func integerSchema(minimum int64, maximum uint64, pattern string) schema {
+	return schema{"anyOf": integerSchemas(minimum, maximum, pattern)}
+}
+
+// integerSchemas returns the alternative schemas of an integer.
+func integerSchemas(minimum int64, maximum uint64, pattern string) []any {
+	return []any{
+		schema{"type": "integer", "minimum": minimum, "maximum": maximum},
+		schema{"type": "string", "pattern": pattern},
+	}
+}
+
+// getFieldKeys returns the mapping keys that may be used for the given field.
+func getFieldKeys(field protoreflect.FieldDescriptor) []string {
+	if field.IsExtension() {
+		return []string{"[" + string(field.FullName()) + "]"}
+	}
+	number := strconv.Itoa(int(field.Number()))
+	if field.JSONName() == field.TextName() {
+		return []string{field.JSONName(), number}
+	}
+	return []string{field.JSONName(), field.TextName(), number}
+}
+
+// requireAny returns a schema that requires at least one of the given keys.
+func requireAny(keys []string) schema {
+	if len(keys) == 1 {
+		return schema{"required": keys}
+	}
+	options := make([]any, 0, len(keys))
+	for _, key := range keys {
+		options = append(options, schema{"required": []string{key}})
+	}
+	return schema{"anyOf": options}
+}
+
+// atMostOne returns a schema that allows at most one of the given keys.
+func atMostOne(keys []string) schema {
+	var pairs []any
+	for i := range keys {
+		for j := i + 1; j < len(keys); j++ {
+			pairs = append(pairs, schema{"required": []string{keys[i], keys[j]}})
+		}
+	}
+	return schema{"not": schema{"anyOf": pairs}}
+}
+
+// withDescription sets the description of the given schema. A reference is
+// wrapped, as keywords next to "$ref" are ignored.
+func withDescription(result schema, description string) schema {
+	switch {
+	case description == "":
+		return result
+	case result["$ref"] != nil:
+		return schema{"description": description, "allOf": []any{result}}
+	default:
+		result["description"] = description
+		return result
+	}
+}
+
+// getComment returns the leading comment of the given descriptor in its
+// .proto file, or an empty string if there is none.
+func getComment(desc protoreflect.Descriptor) string {
+	file := desc.ParentFile()
+	if file == nil {
+		return ""
+	}
+	comment := strings.TrimSpace(file.SourceLocations().ByDescriptor(desc).LeadingComments)
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,231 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"buf.build/go/protoyaml"
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testAllTypesName = "bufext.cel.expr.conformance.proto3.TestAllTypes"
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	data, err := Generate((&testv1.EditionsTest{}).ProtoReflect().Descriptor())
+	require.NoError(t, err)
+	defs := getDefinitions(t, data)
+	assert.Equal(t, SchemaVersion, decodeSchema(t, data)["$schema"])
+
+	editions := defs["buf.protoyaml.test.v1.EditionsTest"]
+	assert.Equal(t, "object", editions["type"])
+	assert.Equal(t, false, editions["additionalProperties"])
+	assert.Equal(t, []any{map[string]any{"anyOf": []any{
+		map[string]any{"required": []any{"name"}},
+		map[string]any{"required": []any{"1"}},
+	}}}, editions["allOf"])
+	properties := getObject(t, editions, "properties")
+	assert.ElementsMatch(t, []string{"name", "1", "nested", "Nested", "2", "enum", "3"}, keys(properties))
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/buf.protoyaml.test.v1.OpenEnum"}, properties["enum"])
+
+	nested := defs["buf.protoyaml.test.v1.EditionsTest.Nested"]
+	assert.Equal(t, []any{"object", "null"}, nested["type"])
+
+	openEnum := defs["buf.protoyaml.test.v1.OpenEnum"]
+	anyOf, ok := openEnum["anyOf"].([]any)
+	require.True(t, ok)
+	require.Len(t, anyOf, 3)
+	assert.Equal(t, map[string]any{"enum": []any{"OPEN_ENUM_UNSPECIFIED"}}, anyOf[0])
+}
+
+func TestGenerateOptions(t *testing.T) {
+	t.Parallel()
+	desc := (&testv1.EditionsTest{}).ProtoReflect().Descriptor()
+	data, err := Options{AllowPartial: true, DiscardUnknown: true}.Generate(desc)
+	require.NoError(t, err)
+	editions := getDefinitions(t, data)["buf.protoyaml.test.v1.EditionsTest"]
+	assert.Equal(t, []any{"object", "null"}, editions["type"])
+	assert.NotContains(t, editions, "additionalProperties")
+	assert.NotContains(t, editions, "allOf")
+}
+
+func TestGenerateFieldNames(t *testing.T) {
+	t.Parallel()
+	data, err := Generate((&proto3.TestAllTypes{}).ProtoReflect().Descriptor())
+	require.NoError(t, err)
+	testAllTypes := getDefinitions(t, data)[testAllTypesName]
+	properties := getObject(t, testAllTypes, "properties")
+	assert.Contains(t, properties, "singleInt32")
+	assert.Equal(t, properties["singleInt32"], properties["single_int32"])
+	assert.Equal(t, properties["singleInt32"], properties["1"])
+	assert.Contains(t, properties, "mapBoolBool")
+	assert.Equal(t, map[string]any{"enum": []any{"true", "false"}}, getObject(t, getObject(t, properties, "mapBoolBool"), "propertyNames"))
+
+	// Only one field of a oneof may be set, by either name.
+	allOf, ok := testAllTypes["allOf"].([]any)
+	require.True(t, ok)
+	require.Len(t, allOf, 1)
+	oneof, ok := allOf[0].(map[string]any)
+	require.True(t, ok)
+	pairs := getObject(t, oneof, "not")["anyOf"]
+	assert.Contains(t, pairs, map[string]any{"required": []any{"singleNestedMessage", "single_nested_message"}})
+	assert.Contains(t, pairs, map[string]any{"required": []any{"singleNestedMessage", "singleNestedEnum"}})
+}
+
+func TestGenerateSingleFieldOneof(t *testing.T) {
+	t.Parallel()
+	// A oneof with a single key needs no constraint.
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("oneof.proto"),
+		Package: proto.String("test.oneof"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Config"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:       proto.String("name"),
+				JsonName:   proto.String("name"),
+				Number:     proto.Int32(1),
+				Label:      descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:       descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				OneofIndex: proto.Int32(0),
+			}},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("kind")}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	data, err := Generate(file.Messages().Get(0))
+	require.NoError(t, err)
+	config := getDefinitions(t, data)["test.oneof.Config"]
+	assert.Contains(t, config, "properties")
+	assert.NotContains(t, config, "allOf")
+}
+
+func TestGenerateExtensions(t *testing.T) {
+	t.Parallel()
+	data, err := Generate((&testv1.Proto2Test{}).ProtoReflect().Descriptor())
+	require.NoError(t, err)
+	properties := getObject(t, getDefinitions(t, data)["buf.protoyaml.test.v1.Proto2Test"], "properties")
+	assert.Contains(t, properties, "[buf.protoyaml.test.v1.p2t_string_ext]")
+	assert.Equal(t, "array", getObject(t, properties, "[buf.protoyaml.test.v1.p2t_repeated_string_ext]")["type"])
+}
+
+func TestGeneratePatterns(t *testing.T) {
+	t.Parallel()
+	// The patterns of string values agree with the unmarshaler.
+	data, err := Generate((&proto3.TestAllTypes{}).ProtoReflect().Descriptor())
+	require.NoError(t, err)
+	defs := getDefinitions(t, data)
+	properties := getObject(t, defs[testAllTypesName], "properties")
+	tests := []struct {
+		Name   string
+		Schema map[string]any
+		Inputs []string
+	}{
+		{
+			Name:   "singleInt64",
+			Schema: getObject(t, properties, "singleInt64"),
+			Inputs: []string{"1", "-1", "+1", "0x1F", "0o17", "0b101", "1e3", "1.0", "1.5", "1Ki", "1.5M", "-2Gi", "1KB", "one", ""},
+		},
+		{
+			Name:   "singleUint32",
+			Schema: getObject(t, properties, "singleUint32"),
+			Inputs: []string{"1", "-1", "0x10", "1k", "1.5", "1e2", "1 k"},
+		},
+		{
+			Name:   "singleDouble",
+			Schema: getObject(t, properties, "singleDouble"),
+			Inputs: []string{"1", "-1.5", ".5", "1e-3", "inf", "-Infinity", "NaN", "1Ki", "one", "1.2.3"},
+		},
+		{
+			Name:   "singleNestedEnum",
+			Schema: defs[testAllTypesName+".NestedEnum"],
+			Inputs: []string{"1", "-1", "0x2", "1.0", "1Ki", "one"},
+		},
+		{
+			Name:   "singleDuration",
+			Schema: defs["google.protobuf.Duration"],
+			Inputs: []string{"1s", "1.5h", "-1m30s", "+1ms", "1us", "1µs", "1ns", "0", "1", "1d", "s", "1.5", ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			pattern := regexp.MustCompile(findPattern(t, test.Schema))
+			for _, input := range test.Inputs {
+				err := protoyaml.Unmarshal([]byte(test.Name+": "+strconv.Quote(input)), &proto3.TestAllTypes{})
+				assert.Equal(t, err == nil, pattern.MatchString(input), "%q: %v", input, err)
+			}
+		})
+	}
+}
+
+func decodeSchema(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+func getDefinitions(t *testing.T, data []byte) map[string]map[string]any {
+	t.Helper()
+	result := make(map[string]map[string]any)
+	for name, def := range getObject(t, decodeSchema(t, data), "definitions") {
+		defObject, ok := def.(map[string]any)
+		require.True(t, ok)
+		result[name] = defObject
+	}
+	return result
+}
+
+func getObject(t *testing.T, object map[string]any, key string) map[string]any {
+	t.Helper()
+	result, ok := object[key].(map[string]any)
+	require.True(t, ok, "%s is not an object", key)
+	return result
+}
+
+// findPattern returns the pattern of the first string alternative of the
+// given schema.
+func findPattern(t *testing.T, object map[string]any) string {
+	t.Helper()
+	anyOf, ok := object["anyOf"].([]any)
+	require.True(t, ok)
+	for _, alternative := range anyOf {
+		if pattern, ok := alternative.(map[string]any)["pattern"].(string); ok {
+			return pattern
+		}
+	}
+	require.Fail(t, "no pattern")
+	return ""
+}
+
+func keys(object map[string]any) []string {
+	result := make([]string, 0, len(object))
+	for key := range object {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultMaxAliasExpansion is the maximum number of YAML nodes that may be
+// produced by expanding aliases, if UnmarshalOptions.MaxAliasExpansion is 0.
+const DefaultMaxAliasExpansion = 100000
+
+// limitChecker enforces the limits in UnmarshalOptions on a document before its
+// aliases are expanded.
+type limitChecker struct {
+	unm *unmarshaler
+	// The maximum number of nodes produced by expanding aliases, or 0 if there
+	// is no limit.
+	maxAliasExpansion int
+	// The number of nodes seen so far, with aliases expanded.
+	nodes int
+	// The number of nodes produced by expanding aliases so far.
+	aliasNodes int
+	// The size and height of each anchor, with aliases expanded.
+	anchors map[*yaml.Node]nodeExtent
+}
+
+// nodeExtent is the number of nodes in a subtree, and its height.
+type nodeExtent struct {
+	size   int
+	height int
+}
+
+// checkLimits reports an error and returns false if the given node exceeds
+// MaxDepth, MaxNodes or MaxAliasExpansion.
+func (u *unmarshaler) checkLimits(node *yaml.Node) bool {
+	maxAliasExpansion := u.options.MaxAliasExpansion
+	switch {
+	case maxAliasExpansion == 0:
+		maxAliasExpansion = DefaultMaxAliasExpansion
+	case maxAliasExpansion < 0:
+		maxAliasExpansion = 0
+	}
+	if u.options.MaxDepth <= 0 && u.options.MaxNodes <= 0 && maxAliasExpansion == 0 {
+		return true
+	}
+	checker := &limitChecker{
+		unm:               u,
+		maxAliasExpansion: maxAliasExpansion,
+		anchors:           make(map[*yaml.Node]nodeExtent),
+	}
+	return checker.check(node, 1)
+}
+
+func (c *limitChecker) check(node *yaml.Node, depth int) bool {
+	options := c.unm.options
+	if options.MaxDepth > 0 && depth > options.MaxDepth {
+		c.unm.addErrorf(node, "exceeded maximum depth of %d", options.MaxDepth)
+		return false
+	}
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		extent := c.measure(node.Alias)
+		if options.MaxDepth > 0 && depth+extent.height-1 > options.MaxDepth {
+			c.unm.addErrorf(node, "exceeded maximum depth of %d", options.MaxDepth)
+			return false
+		}
+		c.aliasNodes = addSaturating(c.aliasNodes, extent.size)
+		if c.maxAliasExpansion > 0 && c.aliasNodes > c.maxAliasExpansion {
+			c.unm.addErrorf(node, "exceeded maximum alias expansion of %d nodes", c.maxAliasExpansion)
+			return false
+		}
+		return c.addNodes(node, extent.size)
+	}
+	if !c.addNodes(node, 1) {
+		return false
+	}
+	for _, child := range node.Content {
+		if !c.check(child, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *limitChecker) addNodes(node *yaml.Node, count int) bool {
+	c.nodes = addSaturating(c.nodes, count)
+	if maxNodes := c.unm.options.MaxNodes; maxNodes > 0 && c.nodes > maxNodes {
+		c.unm.addErrorf(node, "exceeded maximum of %d nodes", maxNodes)
+		return false
+	}
+	return true
+}
+
+// measure returns the extent of the given node once its aliases are expanded,
+// without expanding them.
+func (c *limitChecker) measure(node *yaml.Node) nodeExtent {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return c.measure(node.Alias)
+	}
+	if extent, ok := c.anchors[node]; ok {
+		return extent
+	}
+	extent := nodeExtent{size: 1, height: 1}
+	if node.Anchor != "" {
+		c.anchors[node] = extent // In case the node contains an alias to itself.
+	}
+	for _, child := range node.Content {
+		childExtent := c.measure(child)
+		extent.size = addSaturating(extent.size, childExtent.size)
+		extent.height = max(extent.height, childExtent.height+1)
+	}
+	if node.Anchor != "" {
+		c.anchors[node] = extent
+	}
+	return extent
+}
+
+func addSaturating(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// newDocumentTooLargeError returns an error positioned at the first byte of
+// data beyond MaxDocumentBytes.
+//
+// The data starts after lineOffset lines of the original input.
+func (o UnmarshalOptions) newDocumentTooLargeError(data []byte, lineOffset int) error {
+	prefix := data[:min(len(data), o.MaxDocumentBytes)]
+	lineStart := bytes.LastIndexByte(prefix, '\n') + 1
+	lineEnd := bytes.IndexByte(data[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(data) - lineStart
+	}
+	position := &yaml.Node{
+		Line:   lineOffset + bytes.Count(prefix, []byte{'\n'}) + 1,
+		Column: len([]rune(string(prefix[lineStart:]))) + 1,
+	}
+	line := string(bytes.TrimSuffix(data[lineStart:lineStart+lineEnd], []byte{'\r'}))
+	cause := fmt.Errorf("exceeded maximum document size of %d bytes", o.MaxDocumentBytes)
+	return ErrorList{newError(o.Path, position, line, cause)}
+}
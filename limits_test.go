@@ -0,0 +1,138 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const billionLaughs = `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Name    string
+		Options UnmarshalOptions
+		Input   string
+		Error   string
+	}{
+		{
+			Name:    "AliasExpansion",
+			Options: UnmarshalOptions{MaxAliasExpansion: 1000},
+			Input:   billionLaughs,
+			Error:   "test.yaml:4:8 exceeded maximum alias expansion of 1000 nodes\n   4 | d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]\n   4 | .......^\n",
+		},
+		{
+			Name:    "DefaultAliasExpansion",
+			Options: UnmarshalOptions{},
+			Input:   billionLaughs,
+			Error:   "test.yaml:6:8 exceeded maximum alias expansion of 100000 nodes\n",
+		},
+		{
+			Name:    "Nodes",
+			Options: UnmarshalOptions{MaxNodes: 1000},
+			Input:   billionLaughs,
+			Error:   "test.yaml:4:8 exceeded maximum of 1000 nodes\n",
+		},
+		{
+			Name:    "Depth",
+			Options: UnmarshalOptions{MaxDepth: 3},
+			Input:   "a:\n  b:\n    c: [d]\n",
+			Error:   "test.yaml:3:5 exceeded maximum depth of 3\n",
+		},
+		{
+			Name:    "DepthThroughAlias",
+			Options: UnmarshalOptions{MaxDepth: 4},
+			Input:   "a: &a\n  b: [c]\nd:\n  e: *a\n",
+			Error:   "test.yaml:4:6 exceeded maximum depth of 4\n",
+		},
+		{
+			Name:    "SelfAlias",
+			Options: UnmarshalOptions{MaxDepth: 10},
+			Input:   "a: &a [*a]\n",
+			Error:   "test.yaml:1:8 alias *a refers to its own anchor\n",
+		},
+		{
+			Name:    "DocumentBytes",
+			Options: UnmarshalOptions{MaxDocumentBytes: 12},
+			Input:   "a: 1\nb: 123456\n",
+			Error:   "test.yaml:2:8 exceeded maximum document size of 12 bytes\n   2 | b: 123456\n   2 | .......^\n",
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			testCase.Options.Path = "test.yaml"
+			err := testCase.Options.Unmarshal([]byte(testCase.Input), &structpb.Value{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+}
+
+func TestLimitsWithinBounds(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{
+		MaxDepth:          3,
+		MaxAliasExpansion: 2,
+		MaxDocumentBytes:  40,
+		MaxNodes:          11,
+	}
+	actual := &structpb.Value{}
+	require.NoError(t, options.Unmarshal([]byte("a: &a [1]\nb: *a\nc: {d: e}\n"), actual))
+	assert.Len(t, actual.GetStructValue().GetFields(), 3)
+}
+
+func TestNoAliasExpansionLimit(t *testing.T) {
+	t.Parallel()
+	input := strings.Join(strings.Split(billionLaughs, "\n")[:6], "\n")
+	actual := &structpb.Value{}
+	require.Error(t, Unmarshal([]byte(input), actual))
+	require.NoError(t, UnmarshalOptions{MaxAliasExpansion: -1}.Unmarshal([]byte(input), actual))
+	assert.Len(t, actual.GetStructValue().GetFields()["f"].GetListValue().GetValues(), 9)
+}
+
+func TestDecoderMaxDocumentBytes(t *testing.T) {
+	t.Parallel()
+	data := "name: first\n---\nname: " + strings.Repeat("x", 10000) + "\n...\n---\nname: third\n"
+	decoder := NewDecoder(strings.NewReader(data), UnmarshalOptions{MaxDocumentBytes: 20})
+
+	actual := &testv1.EditionsTest{}
+	require.NoError(t, decoder.Decode(actual))
+	assert.Equal(t, "first", actual.GetName())
+
+	err := decoder.Decode(&testv1.EditionsTest{})
+	require.ErrorContains(t, err, ":3:17 exceeded maximum document size of 20 bytes")
+
+	actual = &testv1.EditionsTest{}
+	require.NoError(t, decoder.Decode(actual))
+	assert.Equal(t, "third", actual.GetName())
+	require.ErrorIs(t, decoder.Decode(actual), io.EOF)
+}
@@ -0,0 +1,169 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config maps the YAML files of a project to their message types.
+//
+//	files:
+//	  - pattern: deploy/**/*.yaml
+//	    message: acme.deploy.v1.Service
+//	  - pattern: config/*.yaml
+//	    message: acme.config.v1.Config
+//	    discard_unknown: true
+//	exclude:
+//	  - deploy/testdata/**
+type Config struct {
+	// Files are the file patterns to lint. The first matching pattern applies
+	// to each file; files that match no pattern are not linted.
+	Files []FileConfig `yaml:"files"`
+	// Exclude are patterns of files and directories that are not linted.
+	Exclude []string `yaml:"exclude"`
+}
+
+// FileConfig selects the message type and options for the files that match
+// a pattern.
+type FileConfig struct {
+	// Pattern matches slash-separated paths relative to the root of the
+	// project, as in path.Match. A `**` element matches any number of
+	// directories.
+	Pattern string `yaml:"pattern"`
+	// Message is the full name of the message type of the matching files.
+	Message string `yaml:"message"`
+	// DiscardUnknown allows unknown fields, as in protoyaml.UnmarshalOptions.
+	DiscardUnknown bool `yaml:"discard_unknown"`
+	// AllowPartial allows missing required fields, as in
+	// protoyaml.UnmarshalOptions.
+	AllowPartial bool `yaml:"allow_partial"`
+}
+
+// ParseConfig parses the given YAML config. Unknown keys are an error.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// LoadConfig reads and parses the config at the given path of fsys.
+func LoadConfig(fsys fs.FS, name string) (*Config, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	for i, file := range c.Files {
+		switch {
+		case file.Pattern == "":
+			return fmt.Errorf("invalid config: files[%d]: missing pattern", i)
+		case file.Message == "":
+			return fmt.Errorf("invalid config: files[%d]: missing message", i)
+		}
+		if err := checkPattern(file.Pattern); err != nil {
+			return fmt.Errorf("invalid config: files[%d]: %w", i, err)
+		}
+	}
+	for i, pattern := range c.Exclude {
+		if err := checkPattern(pattern); err != nil {
+			return fmt.Errorf("invalid config: exclude[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// FindFile returns the config of the given slash-separated path, relative to
+// the root of the project, or nil if the file is not linted.
+func (c *Config) FindFile(name string) *FileConfig {
+	if c.isExcluded(name) {
+		return nil
+	}
+	for i := range c.Files {
+		if matchPattern(c.Files[i].Pattern, name) {
+			return &c.Files[i]
+		}
+	}
+	return nil
+}
+
+// isExcluded returns true if the given path matches an exclude pattern.
+func (c *Config) isExcluded(name string) bool {
+	for _, pattern := range c.Exclude {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPattern returns an error if the given pattern is malformed.
+func checkPattern(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchPattern reports whether the given slash-separated path matches the
+// pattern, where a `**` element matches zero or more path elements.
+func matchPattern(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(elems) + 1 {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
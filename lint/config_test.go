@@ -0,0 +1,112 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+	config, err := ParseConfig([]byte(`files:
+  - pattern: deploy/**/*.yaml
+    message: acme.v1.Service
+    discard_unknown: true
+exclude:
+  - deploy/testdata
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Files:   []FileConfig{{Pattern: "deploy/**/*.yaml", Message: "acme.v1.Service", DiscardUnknown: true}},
+		Exclude: []string{"deploy/testdata"},
+	}, config)
+
+	config, err = ParseConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{}, config)
+}
+
+func TestParseConfigError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		Name  string
+		Input string
+		Error string
+	}{
+		{
+			Name:  "unknown key",
+			Input: "files:\n  - pattern: '*.yaml'\n    type: acme.v1.Service\n",
+			Error: "field type not found",
+		},
+		{
+			Name:  "missing pattern",
+			Input: "files:\n  - message: acme.v1.Service\n",
+			Error: "files[0]: missing pattern",
+		},
+		{
+			Name:  "missing message",
+			Input: "files:\n  - pattern: '*.yaml'\n",
+			Error: "files[0]: missing message",
+		},
+		{
+			Name:  "invalid pattern",
+			Input: "exclude: ['[a']\n",
+			Error: `exclude[0]: invalid pattern "[a"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseConfig([]byte(test.Input))
+			require.ErrorContains(t, err, test.Error)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{"lint.yaml": {Data: []byte("files: 1\n")}}
+	_, err := LoadConfig(fsys, "lint.yaml")
+	require.ErrorContains(t, err, "lint.yaml: invalid config")
+	_, err = LoadConfig(fsys, "missing.yaml")
+	require.Error(t, err)
+}
+
+func TestMatchPattern(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		Pattern string
+		Path    string
+		Match   bool
+	}{
+		{Pattern: "*.yaml", Path: "a.yaml", Match: true},
+		{Pattern: "*.yaml", Path: "dir/a.yaml", Match: false},
+		{Pattern: "dir/*.yaml", Path: "dir/a.yaml", Match: true},
+		{Pattern: "**/*.yaml", Path: "a.yaml", Match: true},
+		{Pattern: "**/*.yaml", Path: "a/b/c.yaml", Match: true},
+		{Pattern: "a/**/c.yaml", Path: "a/c.yaml", Match: true},
+		{Pattern: "a/**/c.yaml", Path: "a/b/b/c.yaml", Match: true},
+		{Pattern: "a/**/c.yaml", Path: "b/c.yaml", Match: false},
+		{Pattern: "a/**", Path: "a/b/c.yaml", Match: true},
+		{Pattern: "a", Path: "a/b", Match: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Match, matchPattern(test.Pattern, test.Path), "%s %s", test.Pattern, test.Path)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint validates the YAML files of a project against their Protobuf
+// message types, as mapped by a Config.
+package lint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"sync"
+
+	"buf.build/go/protoyaml"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Linter validates the files of a project.
+type Linter struct {
+	// Config maps the files of the project to their message types.
+	Config *Config
+	// Resolver is used to find the message types of the files, and the types
+	// within them. If nil, protoregistry.GlobalTypes is used.
+	Resolver interface {
+		protoregistry.MessageTypeResolver
+		protoregistry.ExtensionTypeResolver
+	}
+	// Validator, if set, validates each message after it is unmarshaled. It
+	// must be safe for concurrent use.
+	Validator protoyaml.Validator
+	// Workers is the number of files validated in parallel. If 0, uses
+	// runtime.GOMAXPROCS.
+	Workers int
+}
+
+// Lint validates every file in fsys that matches the config, and returns the
+// errors found, sorted by file and position. Files may contain multiple
+// documents. The paths of `!include` tags are relative to the including file,
+// and may not refer to parent directories.
+//
+// The returned error is only set if the files could not be validated, such as
+// if a message type is not found.
+func (l Linter) Lint(ctx context.Context, fsys fs.FS) (protoyaml.ErrorList, error) {
+	jobs, err := l.findFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	workers := l.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobChan := make(chan lintJob)
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		result   protoyaml.ErrorList
+		firstErr error
+	)
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				errs, err := l.lintFile(fsys, job)
+				mutex.Lock()
+				result = append(result, errs...)
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobChan <- job
+	}
+	close(jobChan)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		x, y := result[i], result[j]
+		switch {
+		case x.Path != y.Path:
+			return x.Path < y.Path
+		case x.Line != y.Line:
+			return x.Line < y.Line
+		default:
+			return x.Column < y.Column
+		}
+	})
+	return result, nil
+}
+
+// lintJob is a file to validate.
+type lintJob struct {
+	path    string
+	config  *FileConfig
+	msgType protoreflect.MessageType
+}
+
+// findFiles returns the files in fsys to validate.
+func (l Linter) findFiles(fsys fs.FS) ([]lintJob, error) {
+	if l.Config == nil {
+		return nil, errors.New("missing config")
+	}
+	resolver := l.getResolver()
+	msgTypes := make(map[string]protoreflect.MessageType)
+	for _, file := range l.Config.Files {
+		if _, ok := msgTypes[file.Message]; ok {
+			continue
+		}
+		msgType, err := resolver.FindMessageByName(protoreflect.FullName(file.Message))
+		if err != nil {
+			return nil, fmt.Errorf("message type %s of pattern %s: %w", file.Message, file.Pattern, err)
+		}
+		msgTypes[file.Message] = msgType
+	}
+	var jobs []lintJob
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case name == ".":
+			return nil
+		case entry.IsDir():
+			if l.Config.isExcluded(name) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if file := l.Config.FindFile(name); file != nil {
+			jobs = append(jobs, lintJob{path: name, config: file, msgType: msgTypes[file.Message]})
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+// lintFile validates each document of the given file.
+func (l Linter) lintFile(fsys fs.FS, job lintJob) (protoyaml.ErrorList, error) {
+	file, err := fsys.Open(job.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	includeFS, err := fs.Sub(fsys, path.Dir(job.path))
+	if err != nil {
+		return nil, err
+	}
+	decoder := protoyaml.NewDecoder(file, protoyaml.UnmarshalOptions{
+		Path:           job.path,
+		Resolver:       l.getResolver(),
+		Validator:      l.Validator,
+		DiscardUnknown: job.config.DiscardUnknown,
+		AllowPartial:   job.config.AllowPartial,
+		IncludeFS:      includeFS,
+	})
+	var result protoyaml.ErrorList
+	for {
+		err := decoder.Decode(job.msgType.New().Interface())
+		var errs protoyaml.ErrorList
+		switch {
+		case errors.Is(err, io.EOF):
+			return result, nil
+		case errors.As(err, &errs):
+			result = append(result, errs...)
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", job.path, err)
+		}
+	}
+}
+
+func (l Linter) getResolver() interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+} {
+	if l.Resolver != nil {
+		return l.Resolver
+	}
+	return protoregistry.GlobalTypes
+}
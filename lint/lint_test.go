@@ -0,0 +1,106 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	_ "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+)
+
+type testValidator struct {
+	validator protovalidate.Validator
+}
+
+func (v *testValidator) Validate(message proto.Message) error {
+	return v.validator.Validate(message)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	config, err := ParseConfig([]byte(`files:
+  - pattern: validate/*.yaml
+    message: buf.protoyaml.test.v1.ValidateTest
+  - pattern: lenient/**
+    message: buf.protoyaml.test.v1.Proto3Test
+    discard_unknown: true
+  - pattern: "**/*.yaml"
+    message: buf.protoyaml.test.v1.Proto3Test
+exclude:
+  - b/testdata
+  - "**/fragments"
+`))
+	require.NoError(t, err)
+	fsys := fstest.MapFS{
+		"a.yaml":                        {Data: []byte("values:\n  - singleInt32: x\n")},
+		"b/b.yaml":                      {Data: []byte("values: []\n---\nvalue: []\n---\nvalues: [{singleBool: 1}, {singleInt32: x}]\n")},
+		"b/testdata/bad.yaml":           {Data: []byte("bad: []\n")},
+		"b/notes.txt":                   {Data: []byte("bad: []\n")},
+		"lenient/nested/c.yaml":         {Data: []byte("unknown: 1\nvalues: []\n")},
+		"validate/d.yaml":               {Data: []byte("cases:\n  - floatGtLt: 11\n")},
+		"include/e.yaml":                {Data: []byte("values: !include fragments/values.yaml\n")},
+		"include/fragments/values.yaml": {Data: []byte("- singleInt64: 1Ki\n- singleInt64: x\n")},
+	}
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+	errs, err := Linter{
+		Config:    config,
+		Validator: &testValidator{validator: validator},
+		Workers:   2,
+	}.Lint(context.Background(), fsys)
+	require.NoError(t, err)
+	type position struct {
+		Path   string
+		Line   int
+		Column int
+	}
+	positions := make([]position, 0, len(errs))
+	for _, err := range errs {
+		positions = append(positions, position{Path: err.Path, Line: err.Line, Column: err.Column})
+	}
+	assert.Equal(t, []position{
+		{Path: "a.yaml", Line: 2, Column: 18},
+		{Path: "b/b.yaml", Line: 3, Column: 1},
+		{Path: "b/b.yaml", Line: 5, Column: 23},
+		{Path: "b/b.yaml", Line: 5, Column: 41},
+		{Path: "include/fragments/values.yaml", Line: 2, Column: 16},
+		{Path: "validate/d.yaml", Line: 2, Column: 16},
+	}, positions)
+}
+
+func TestLintError(t *testing.T) {
+	t.Parallel()
+	config, err := ParseConfig([]byte("files:\n  - pattern: '*.yaml'\n    message: buf.protoyaml.test.v1.Missing\n"))
+	require.NoError(t, err)
+	_, err = Linter{Config: config}.Lint(context.Background(), fstest.MapFS{})
+	require.ErrorContains(t, err, "message type buf.protoyaml.test.v1.Missing of pattern *.yaml")
+
+	_, err = Linter{}.Lint(context.Background(), fstest.MapFS{})
+	require.ErrorContains(t, err, "missing config")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	config, err = ParseConfig([]byte("files:\n  - pattern: '*.yaml'\n    message: buf.protoyaml.test.v1.Proto3Test\n"))
+	require.NoError(t, err)
+	_, err = Linter{Config: config}.Lint(ctx, fstest.MapFS{"a.yaml": {Data: []byte("values: []\n")}})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,635 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	anyFullName     = "google.protobuf.Any"
+	atTypeFieldName = "@type"
+	typeURLPrefix   = "type.googleapis.com/"
+	// The value that replaces the word being completed, to find its node.
+	placeholder = "__protoyaml_completion__"
+)
+
+// analysis finds the Protobuf elements that the nodes of a document refer to.
+type analysis struct {
+	session *session
+	doc     *document
+	message protoreflect.MessageDescriptor
+	lines   []string
+}
+
+func (s *session) newAnalysis(doc *document, message protoreflect.MessageDescriptor) *analysis {
+	return &analysis{
+		session: s,
+		doc:     doc,
+		message: message,
+		lines:   strings.Split(doc.text, "\n"),
+	}
+}
+
+// targetKind is the role of a node in a message.
+type targetKind int
+
+const (
+	// The key of a field.
+	targetKey targetKind = iota + 1
+	// A node in place of a message, which is not a mapping.
+	targetMessage
+	// A value of a field, which is not a message.
+	targetValue
+	// The `@type` value of a google.protobuf.Any message.
+	targetTypeURL
+)
+
+// target is the Protobuf element that a node refers to.
+type target struct {
+	kind targetKind
+	node *yaml.Node
+	// The message of a key, or of a node in place of a message. Nil if the
+	// type of a google.protobuf.Any message is not known.
+	message protoreflect.MessageDescriptor
+	// The mapping that contains a key.
+	mapping *yaml.Node
+	// The field of a key or value, if known.
+	field protoreflect.FieldDescriptor
+}
+
+// complete returns the completions at the given position: the fields of a
+// message, the values of an enum or bool field, or the `@type` URLs of the
+// known message types.
+func (a *analysis) complete(pos position) any {
+	if pos.Line < 0 || pos.Line >= len(a.lines) {
+		return nil
+	}
+	line := a.lines[pos.Line]
+	cursor := byteOffset(line, pos.Character)
+	start, end := cursor, cursor
+	for start > 0 && isWordByte(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordByte(line[end]) {
+		end++
+	}
+	rest := line[end:]
+	hasColon := strings.HasPrefix(strings.TrimLeft(rest, " "), ":")
+	// A word on its own line is a key, but only parses as one with a colon.
+	for _, suffix := range []string{"", ": "} {
+		lines := append([]string{}, a.lines...)
+		lines[pos.Line] = line[:start] + placeholder + suffix + rest
+		roots, err := parseDocuments(strings.Join(lines, "\n"))
+		if err != nil {
+			continue
+		}
+		found := a.find(roots, func(node *yaml.Node) bool {
+			return node.Kind == yaml.ScalarNode && node.Value == placeholder
+		})
+		if found == nil {
+			continue
+		}
+		rng := lspRange{
+			Start: position{Line: pos.Line, Character: utf16Length(line, utf8.RuneCountInString(line[:start]))},
+			End:   position{Line: pos.Line, Character: utf16Length(line, utf8.RuneCountInString(line[:end]))},
+		}
+		return completionList{Items: a.completionItems(found, rng, hasColon)}
+	}
+	return nil
+}
+
+// completionItems returns the completions of the given target, replacing the
+// given range. If hasColon is false, keys are completed with a colon.
+func (a *analysis) completionItems(found *target, rng lspRange, hasColon bool) []completionItem {
+	result := &completions{items: []completionItem{}, rng: rng, hasColon: hasColon}
+	switch found.kind {
+	case targetKey, targetMessage:
+		a.completeFields(result, found)
+	case targetValue:
+		switch {
+		case found.field.Enum() != nil:
+			values := found.field.Enum().Values()
+			for i := range values.Len() {
+				result.add(string(values.Get(i).Name()), completionKindEnumValue, string(found.field.Enum().FullName()), values.Get(i))
+			}
+		case found.field.Kind() == protoreflect.BoolKind:
+			result.add("true", completionKindValue, "bool", nil)
+			result.add("false", completionKindValue, "bool", nil)
+		}
+	case targetTypeURL:
+		for _, msgType := range a.rangeMessages() {
+			desc := msgType.Descriptor()
+			result.add(typeURLPrefix+string(desc.FullName()), completionKindClass, "message", desc)
+		}
+	}
+	return result.items
+}
+
+// completeFields adds the fields of the message of the given target that are
+// not set yet, or are not in a oneof that is set.
+func (a *analysis) completeFields(result *completions, found *target) {
+	if found.message == nil {
+		return
+	}
+	message := found.message
+	if message.FullName() == anyFullName {
+		if !a.hasKey(found.mapping, atTypeFieldName) {
+			result.add(atTypeFieldName, completionKindField, "string", nil)
+		}
+		if message = a.findAnyType(found.mapping); message == nil {
+			return
+		}
+	}
+	set := a.findSetFields(found.mapping, message)
+	for i := range message.Fields().Len() {
+		field := message.Fields().Get(i)
+		if oneof := field.ContainingOneof(); set[field.FullName()] || (oneof != nil && !oneof.IsSynthetic() && set[oneof.FullName()]) {
+			continue
+		}
+		result.add(field.JSONName(), completionKindField, getFieldType(field), field)
+	}
+}
+
+// completions are the completion items that replace a range.
+type completions struct {
+	items []completionItem
+	rng   lspRange
+	// Whether the key being completed is followed by a colon.
+	hasColon bool
+}
+
+// add adds an item with the comments of the given descriptor, if any.
+func (c *completions) add(label string, kind int, detail string, desc protoreflect.Descriptor) {
+	item := completionItem{
+		Label:    label,
+		Kind:     kind,
+		Detail:   detail,
+		TextEdit: &textEdit{Range: c.rng, NewText: label},
+	}
+	if label == atTypeFieldName {
+		item.TextEdit.NewText = "'" + label + "'" // `@` cannot start a plain scalar.
+	}
+	if kind == completionKindField && !c.hasColon {
+		item.TextEdit.NewText += ": "
+	}
+	if desc != nil {
+		if comments := getComments(desc); comments != "" {
+			item.Documentation = &markupContent{Kind: "markdown", Value: comments}
+		}
+	}
+	c.items = append(c.items, item)
+}
+
+// hover returns the type and comments of the field, enum value or message
+// at the given position.
+func (a *analysis) hover(pos position) any {
+	found := a.findAt(pos)
+	if found == nil {
+		return nil
+	}
+	desc := a.getDescriptor(found)
+	if desc == nil {
+		return nil
+	}
+	value := "```proto\n" + getSignature(desc) + "\n```"
+	if comments := getComments(desc); comments != "" {
+		value += "\n\n" + comments
+	}
+	rng := a.nodeRange(found.node)
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    &rng,
+	}
+}
+
+// definition returns the location in the .proto source of the field, enum
+// value or message at the given position.
+func (a *analysis) definition(pos position) any {
+	found := a.findAt(pos)
+	if found == nil {
+		return nil
+	}
+	desc := a.getDescriptor(found)
+	if desc == nil {
+		return nil
+	}
+	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+	if loc.Path == nil {
+		return nil // The descriptor has no source code info.
+	}
+	root := a.session.server.ProtoRoot
+	if root == "" {
+		root = a.session.root
+	}
+	path, err := filepath.Abs(filepath.Join(root, filepath.FromSlash(desc.ParentFile().Path())))
+	if err != nil {
+		return nil
+	}
+	return location{
+		URI: pathToURI(path),
+		Range: lspRange{
+			Start: position{Line: loc.StartLine, Character: loc.StartColumn},
+			End:   position{Line: loc.EndLine, Character: loc.EndColumn},
+		},
+	}
+}
+
+// findAt returns the target of the scalar node at the given position, or nil
+// if there is none or the document cannot be parsed.
+func (a *analysis) findAt(pos position) *target {
+	if pos.Line < 0 || pos.Line >= len(a.lines) {
+		return nil
+	}
+	roots, err := parseDocuments(a.doc.text)
+	if err != nil {
+		return nil
+	}
+	line := a.lines[pos.Line]
+	column := utf8.RuneCountInString(line[:byteOffset(line, pos.Character)]) + 1
+	return a.find(roots, func(node *yaml.Node) bool {
+		return node.Kind == yaml.ScalarNode && node.Line == pos.Line+1 &&
+			node.Column <= column && column <= node.Column+getNodeWidth(node)
+	})
+}
+
+// getDescriptor returns the descriptor of the element that the target refers
+// to, or nil if it is not known.
+func (a *analysis) getDescriptor(found *target) protoreflect.Descriptor {
+	switch {
+	case found.kind == targetTypeURL:
+		msgType, err := a.session.resolver().FindMessageByURL(found.node.Value)
+		if err != nil {
+			return nil
+		}
+		return msgType.Descriptor()
+	case found.field == nil:
+		return nil
+	case found.kind == targetValue && found.field.Enum() != nil:
+		if value := found.field.Enum().Values().ByName(protoreflect.Name(found.node.Value)); value != nil {
+			return value
+		}
+	}
+	return found.field
+}
+
+// nodeRange returns the range of the given scalar node.
+func (a *analysis) nodeRange(node *yaml.Node) lspRange {
+	return lspRange{
+		Start: newPosition(a.lines, node.Line, node.Column),
+		End:   newPosition(a.lines, node.Line, node.Column+getNodeWidth(node)),
+	}
+}
+
+// find returns the target of the first node in the given documents that
+// matches, or nil if none does.
+func (a *analysis) find(roots []*yaml.Node, match func(*yaml.Node) bool) *target {
+	for _, root := range roots {
+		if found := a.findInMessage(root, a.message, nil, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findInMessage searches the given node of a message of the given type. The
+// field is the field of the message, if any.
+func (a *analysis) findInMessage(node *yaml.Node, message protoreflect.MessageDescriptor, field protoreflect.FieldDescriptor, match func(*yaml.Node) bool) *target {
+	if node.Kind != yaml.MappingNode {
+		if match(node) {
+			return &target{kind: targetMessage, node: node, message: message, field: field}
+		}
+		return nil
+	}
+	fields := message
+	isAny := message.FullName() == anyFullName
+	if isAny {
+		fields = a.findAnyType(node)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isAny && key.Value == atTypeFieldName {
+			if match(value) {
+				return &target{kind: targetTypeURL, node: value, message: message}
+			}
+			continue
+		}
+		var keyField protoreflect.FieldDescriptor
+		if fields != nil {
+			keyField = a.findField(key.Value, fields)
+		}
+		if match(key) {
+			return &target{kind: targetKey, node: key, message: message, mapping: node, field: keyField}
+		}
+		if keyField != nil {
+			if found := a.findInField(value, keyField, match); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// findInField searches the given value of a field.
+func (a *analysis) findInField(node *yaml.Node, field protoreflect.FieldDescriptor, match func(*yaml.Node) bool) *target {
+	switch {
+	case field.IsList() && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			if found := a.findInElement(item, field, match); found != nil {
+				return found
+			}
+		}
+		return nil
+	case field.IsMap() && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if match(node.Content[i]) {
+				return &target{kind: targetValue, node: node.Content[i], field: field.MapKey()}
+			}
+			if found := a.findInElement(node.Content[i+1], field.MapValue(), match); found != nil {
+				return found
+			}
+		}
+		return nil
+	case field.IsList() || field.IsMap():
+		if match(node) {
+			return &target{kind: targetValue, node: node, field: field}
+		}
+		return nil
+	default:
+		return a.findInElement(node, field, match)
+	}
+}
+
+// findInElement searches the given value of a singular field, or an element of
+// a list or map field.
+func (a *analysis) findInElement(node *yaml.Node, field protoreflect.FieldDescriptor, match func(*yaml.Node) bool) *target {
+	if message := field.Message(); message != nil && !isScalarMessage(message) {
+		return a.findInMessage(node, message, field, match)
+	}
+	if match(node) {
+		return &target{kind: targetValue, node: node, field: field}
+	}
+	return nil
+}
+
+// findField returns the field with the given key, as found by the
+// unmarshaler, or nil if there is none.
+func (a *analysis) findField(key string, message protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	fields := message.Fields()
+	if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+		extType, err := a.session.resolver().FindExtensionByName(protoreflect.FullName(key[1 : len(key)-1]))
+		if err != nil || extType.TypeDescriptor().ContainingMessage().FullName() != message.FullName() {
+			return nil
+		}
+		return extType.TypeDescriptor()
+	}
+	if field := fields.ByJSONName(key); field != nil {
+		return field
+	}
+	if field := fields.ByTextName(key); field != nil {
+		return field
+	}
+	if num, err := strconv.ParseInt(key, 10, 32); err == nil && num > 0 && num <= math.MaxInt32 {
+		return fields.ByNumber(protoreflect.FieldNumber(num))
+	}
+	return nil
+}
+
+// findAnyType returns the message type of the `@type` URL of the given
+// google.protobuf.Any mapping, or nil if it is not known.
+func (a *analysis) findAnyType(mapping *yaml.Node) protoreflect.MessageDescriptor {
+	if mapping == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == atTypeFieldName {
+			msgType, err := a.session.resolver().FindMessageByURL(mapping.Content[i+1].Value)
+			if err != nil {
+				return nil
+			}
+			return msgType.Descriptor()
+		}
+	}
+	return nil
+}
+
+// hasKey returns true if the given mapping has the given key.
+func (a *analysis) hasKey(mapping *yaml.Node, key string) bool {
+	if mapping == nil {
+		return false
+	}
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// findSetFields returns the full names of the fields of the given message,
+// and of their oneofs, that are set in the given mapping.
+func (a *analysis) findSetFields(mapping *yaml.Node, message protoreflect.MessageDescriptor) map[protoreflect.FullName]bool {
+	set := make(map[protoreflect.FullName]bool)
+	if mapping == nil {
+		return set
+	}
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if field := a.findField(mapping.Content[i].Value, message); field != nil {
+			set[field.FullName()] = true
+			if oneof := field.ContainingOneof(); oneof != nil {
+				set[oneof.FullName()] = true
+			}
+		}
+	}
+	return set
+}
+
+// rangeMessages returns the message types of the resolver, sorted by name, if
+// it can list them.
+func (a *analysis) rangeMessages() []protoreflect.MessageType {
+	resolver, ok := a.session.resolver().(interface {
+		RangeMessages(f func(protoreflect.MessageType) bool)
+	})
+	if !ok {
+		return nil
+	}
+	var result []protoreflect.MessageType
+	resolver.RangeMessages(func(msgType protoreflect.MessageType) bool {
+		if !msgType.Descriptor().IsMapEntry() {
+			result = append(result, msgType)
+		}
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Descriptor().FullName() < result[j].Descriptor().FullName()
+	})
+	return result
+}
+
+// parseDocuments returns the root nodes of the YAML documents in the given
+// text.
+func parseDocuments(text string) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	var roots []*yaml.Node
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		switch {
+		case errors.Is(err, io.EOF):
+			return roots, nil
+		case err != nil:
+			return nil, err
+		case len(document.Content) == 1:
+			roots = append(roots, document.Content[0])
+		}
+	}
+}
+
+// scalarMessages are the well-known types that are not written as a mapping of
+// their fields, as they are unmarshaled by protoyaml. Other types of the
+// google.protobuf package, such as google.protobuf.Empty, are plain messages.
+var scalarMessages = map[protoreflect.FullName]bool{
+	"google.protobuf.Duration":    true,
+	"google.protobuf.Timestamp":   true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.BytesValue":  true,
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.Value":       true,
+	"google.protobuf.ListValue":   true,
+	"google.protobuf.Struct":      true,
+}
+
+// isScalarMessage returns true if the given message is a well-known type that
+// is not written as a mapping of its fields, such as google.protobuf.Duration.
+func isScalarMessage(message protoreflect.MessageDescriptor) bool {
+	return scalarMessages[message.FullName()]
+}
+
+// getSignature returns the declaration of the given descriptor, as written in
+// a .proto file.
+func getSignature(desc protoreflect.Descriptor) string {
+	switch desc := desc.(type) {
+	case protoreflect.FieldDescriptor:
+		name := string(desc.Name())
+		if desc.IsExtension() {
+			name = "[" + string(desc.FullName()) + "]"
+		}
+		return fmt.Sprintf("%s %s = %d", getFieldType(desc), name, desc.Number())
+	case protoreflect.EnumValueDescriptor:
+		return fmt.Sprintf("%s = %d", desc.Name(), desc.Number())
+	case protoreflect.MessageDescriptor:
+		return "message " + string(desc.FullName())
+	default:
+		return string(desc.FullName())
+	}
+}
+
+// getFieldType returns the type of the given field, with its label.
+func getFieldType(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return fmt.Sprintf("map<%s, %s>", getKindName(field.MapKey()), getKindName(field.MapValue()))
+	case field.IsList():
+		return "repeated " + getKindName(field)
+	case field.Cardinality() == protoreflect.Required:
+		return "required " + getKindName(field)
+	case field.HasOptionalKeyword():
+		return "optional " + getKindName(field)
+	default:
+		return getKindName(field)
+	}
+}
+
+func getKindName(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.Message() != nil:
+		return string(field.Message().FullName())
+	case field.Enum() != nil:
+		return string(field.Enum().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
+
+// getComments returns the leading and trailing comments of the given
+// descriptor, if its file has source code info.
+func getComments(desc protoreflect.Descriptor) string {
+	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+	var parts []string
+	for _, comment := range []string{loc.LeadingComments, loc.TrailingComments} {
+		if comment = trimComment(comment); comment != "" {
+			parts = append(parts, comment)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// trimComment removes the space that follows the comment marker from each line
+// of the given comment.
+func trimComment(comment string) string {
+	lines := strings.Split(strings.TrimRight(comment, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// getNodeWidth returns the number of characters of the given scalar node, if
+// it is on a single line.
+func getNodeWidth(node *yaml.Node) int {
+	width := utf8.RuneCountInString(node.Value)
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		width += 2
+	}
+	return width
+}
+
+// isWordByte returns true if the given byte may be part of a word being
+// completed, such as a key, an enum value, or a type URL.
+func isWordByte(b byte) bool {
+	return !strings.ContainsRune(" \t\r:,[]{}#", rune(b))
+}
+
+// byteOffset returns the offset in the given line of the given number of
+// UTF-16 code units.
+func byteOffset(line string, character int) int {
+	for offset, r := range line {
+		if character <= 0 {
+			return offset
+		}
+		character--
+		if r >= 0x10000 {
+			character-- // A surrogate pair.
+		}
+	}
+	return len(line)
+}
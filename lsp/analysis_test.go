@@ -0,0 +1,270 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	client.initialize()
+	tests := []struct {
+		Name   string
+		Input  string
+		Labels []string
+	}{
+		{Name: "empty", Input: "|", Labels: []string{"name", "logLevel", "items", "extra", "url", "path", "enabled"}},
+		{Name: "set fields", Input: "name: a\nurl: b\n|", Labels: []string{"logLevel", "items", "extra", "enabled"}},
+		{Name: "enum", Input: "logLevel: LEVEL_|", Labels: []string{"LEVEL_UNSPECIFIED", "LEVEL_LOW", "LEVEL_HIGH"}},
+		{Name: "bool", Input: "enabled: |", Labels: []string{"true", "false"}},
+		{Name: "string", Input: "name: |", Labels: []string{}},
+		{Name: "list item", Input: "items:\n  - |", Labels: []string{"id", "levels"}},
+		{Name: "list item field", Input: "items:\n  - id: 1\n    l|", Labels: []string{"levels"}},
+		{Name: "flow list", Input: "items:\n  - levels: [LEVEL_LOW, |]", Labels: []string{"LEVEL_UNSPECIFIED", "LEVEL_LOW", "LEVEL_HIGH"}},
+		{Name: "any", Input: "extra:\n  |", Labels: []string{"@type"}},
+		{Name: "any type", Input: "extra:\n  '@type': |", Labels: []string{"type.googleapis.com/acme.config.v1.Config", "type.googleapis.com/acme.config.v1.Item"}},
+		{Name: "any fields", Input: "extra:\n  '@type': type.googleapis.com/acme.config.v1.Item\n  |", Labels: []string{"id", "levels"}},
+		{Name: "second document", Input: "name: a\n---\nna|", Labels: []string{"name", "logLevel", "items", "extra", "url", "path", "enabled"}},
+		{Name: "unknown field", Input: "unknown:\n  |", Labels: nil},
+		{Name: "syntax error", Input: "items: [\n|", Labels: nil},
+	}
+	for _, test := range tests {
+		text, pos := splitCursor(t, test.Input)
+		uri := client.open("config.yaml", text)
+		var result *completionList
+		require.NoError(t, client.request("textDocument/completion", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     pos,
+		}, &result), test.Name)
+		if test.Labels == nil {
+			assert.Nil(t, result, test.Name)
+			continue
+		}
+		require.NotNil(t, result, test.Name)
+		labels := []string{}
+		for _, item := range result.Items {
+			labels = append(labels, item.Label)
+		}
+		assert.Equal(t, test.Labels, labels, test.Name)
+	}
+}
+
+func TestCompleteEdit(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	client.initialize()
+	complete := func(input string) []completionItem {
+		text, pos := splitCursor(t, input)
+		uri := client.open("config.yaml", text)
+		var result completionList
+		require.NoError(t, client.request("textDocument/completion", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     pos,
+		}, &result))
+		return result.Items
+	}
+
+	items := complete("items:\n  - id: 1\n    le|")
+	require.Len(t, items, 1)
+	assert.Equal(t, &textEdit{
+		Range:   lspRange{Start: position{Line: 2, Character: 4}, End: position{Line: 2, Character: 6}},
+		NewText: "levels: ",
+	}, items[0].TextEdit)
+	assert.Equal(t, "repeated acme.config.v1.Level", items[0].Detail)
+	assert.Equal(t, completionKindField, items[0].Kind)
+
+	// Keys that are followed by a colon are replaced whole.
+	items = complete("na|me: a\n")
+	require.NotEmpty(t, items)
+	assert.Equal(t, "name", items[0].Label)
+	assert.Equal(t, &textEdit{
+		Range:   lspRange{Start: position{Line: 0, Character: 0}, End: position{Line: 0, Character: 4}},
+		NewText: "name",
+	}, items[0].TextEdit)
+	assert.Equal(t, &markupContent{Kind: "markdown", Value: "The name of the service.\nMust be unique."}, items[0].Documentation)
+
+	items = complete("extra:\n  |")
+	require.Len(t, items, 1)
+	assert.Equal(t, "'@type': ", items[0].TextEdit.NewText)
+
+	items = complete("logLevel: LEVEL_H|")
+	require.Len(t, items, 3)
+	assert.Equal(t, &textEdit{
+		Range:   lspRange{Start: position{Line: 0, Character: 10}, End: position{Line: 0, Character: 17}},
+		NewText: "LEVEL_HIGH",
+	}, items[2].TextEdit)
+	assert.Equal(t, completionKindEnumValue, items[2].Kind)
+	assert.Equal(t, &markupContent{Kind: "markdown", Value: "Only important messages."}, items[2].Documentation)
+}
+
+func TestHover(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	client.initialize()
+	tests := []struct {
+		Name     string
+		Input    string
+		Contents string
+		Range    lspRange
+	}{
+		{
+			Name:     "field",
+			Input:    "na|me: a\n",
+			Contents: "```proto\nstring name = 1\n```\n\nThe name of the service.\nMust be unique.",
+			Range:    lspRange{Start: position{Line: 0, Character: 0}, End: position{Line: 0, Character: 4}},
+		},
+		{
+			Name:     "proto name",
+			Input:    "log_level|: LEVEL_LOW\n",
+			Contents: "```proto\nacme.config.v1.Level log_level = 2\n```\n\nThe minimum level to log.",
+			Range:    lspRange{Start: position{Line: 0, Character: 0}, End: position{Line: 0, Character: 9}},
+		},
+		{
+			Name:     "enum value",
+			Input:    "logLevel: \"|LEVEL_HIGH\"\n",
+			Contents: "```proto\nLEVEL_HIGH = 2\n```\n\nOnly important messages.",
+			Range:    lspRange{Start: position{Line: 0, Character: 10}, End: position{Line: 0, Character: 22}},
+		},
+		{
+			Name:     "list",
+			Input:    "items:\n  - {id: 1, lev|els: []}\n",
+			Contents: "```proto\nrepeated acme.config.v1.Level levels = 2\n```",
+			Range:    lspRange{Start: position{Line: 1, Character: 12}, End: position{Line: 1, Character: 18}},
+		},
+		{
+			Name:     "type URL",
+			Input:    "extra:\n  '@type': type.googleapis.com/acme.config.v1.It|em\n",
+			Contents: "```proto\nmessage acme.config.v1.Item\n```\n\nAn item.",
+			Range:    lspRange{Start: position{Line: 1, Character: 11}, End: position{Line: 1, Character: 50}},
+		},
+		{Name: "unknown field", Input: "unk|nown: 1\n"},
+		{Name: "whitespace", Input: "name:  |  a\n"},
+		{Name: "syntax error", Input: "na|me: [\n"},
+	}
+	for _, test := range tests {
+		text, pos := splitCursor(t, test.Input)
+		uri := client.open("config.yaml", text)
+		var result *hover
+		require.NoError(t, client.request("textDocument/hover", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     pos,
+		}, &result), test.Name)
+		if test.Contents == "" {
+			assert.Nil(t, result, test.Name)
+			continue
+		}
+		require.NotNil(t, result, test.Name)
+		assert.Equal(t, markupContent{Kind: "markdown", Value: test.Contents}, result.Contents, test.Name)
+		assert.Equal(t, &test.Range, result.Range, test.Name)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+	server.ProtoRoot = t.TempDir()
+	client := newTestClient(t, server)
+	client.initialize()
+	protoURI := pathToURI(filepath.Join(server.ProtoRoot, "acme", "config", "v1", "config.proto"))
+	tests := []struct {
+		Name     string
+		Input    string
+		Location *location
+	}{
+		{
+			Name:     "field",
+			Input:    "|name: a\n",
+			Location: &location{URI: protoURI, Range: lspRange{Start: position{Line: 6, Character: 2}, End: position{Line: 6, Character: 18}}},
+		},
+		{
+			Name:     "enum value",
+			Input:    "logLevel: LEVEL_HIGH|\n",
+			Location: &location{URI: protoURI, Range: lspRange{Start: position{Line: 22, Character: 2}, End: position{Line: 22, Character: 17}}},
+		},
+		{
+			Name:     "type URL",
+			Input:    "extra: {'@type': |type.googleapis.com/acme.config.v1.Item}\n",
+			Location: &location{URI: protoURI, Range: lspRange{Start: position{Line: 14, Character: 0}, End: position{Line: 17, Character: 1}}},
+		},
+		{Name: "no source info", Input: "enab|led: true\n"},
+		{Name: "unknown type URL", Input: "extra: {'@type': |type.googleapis.com/acme.Missing}\n"},
+	}
+	for _, test := range tests {
+		text, pos := splitCursor(t, test.Input)
+		uri := client.open("config.yaml", text)
+		var result *location
+		require.NoError(t, client.request("textDocument/definition", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     pos,
+		}, &result), test.Name)
+		assert.Equal(t, test.Location, result, test.Name)
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	client.initialize()
+	uri := client.open("config.yaml", "name: a\n")
+	for _, pos := range []position{{Line: -1}, {Line: 5}, {Line: 0, Character: -1}} {
+		for _, method := range []string{"textDocument/completion", "textDocument/hover", "textDocument/definition"} {
+			var result any
+			require.NoError(t, client.request(method, textDocumentPositionParams{
+				TextDocument: textDocumentIdentifier{URI: uri},
+				Position:     pos,
+			}, &result), method)
+			if pos.Line != 0 {
+				assert.Nil(t, result, method)
+			}
+		}
+	}
+}
+
+func TestIsScalarMessage(t *testing.T) {
+	t.Parallel()
+	assert.True(t, isScalarMessage((&durationpb.Duration{}).ProtoReflect().Descriptor()))
+	assert.True(t, isScalarMessage((&structpb.Struct{}).ProtoReflect().Descriptor()))
+	assert.True(t, isScalarMessage((&wrapperspb.StringValue{}).ProtoReflect().Descriptor()))
+	// Other types of the package are written as a mapping of their fields.
+	assert.False(t, isScalarMessage((&anypb.Any{}).ProtoReflect().Descriptor()))
+	assert.False(t, isScalarMessage((&emptypb.Empty{}).ProtoReflect().Descriptor()))
+	assert.False(t, isScalarMessage((&fieldmaskpb.FieldMask{}).ProtoReflect().Descriptor()))
+	assert.False(t, isScalarMessage((&descriptorpb.FieldOptions{}).ProtoReflect().Descriptor()))
+}
+
+// splitCursor returns the given input without the `|` that marks the cursor,
+// and the position of the cursor.
+func splitCursor(t *testing.T, input string) (string, position) {
+	t.Helper()
+	index := strings.Index(input, "|")
+	require.GreaterOrEqual(t, index, 0)
+	before := input[:index]
+	line := strings.Count(before, "\n")
+	return before + input[index+1:], position{Line: line, Character: len(before) - strings.LastIndex(before, "\n") - 1}
+}
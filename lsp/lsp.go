@@ -0,0 +1,420 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp implements a Language Server Protocol server for YAML files of
+// Protobuf messages.
+//
+// The server maps the files of a workspace to their message types with a
+// lint.Config, and provides:
+//
+//   - diagnostics for the errors found by protoyaml as the files are edited,
+//   - completion of field names, enum values and `@type` URLs,
+//   - the types and comments of fields on hover, and
+//   - go-to-definition into the .proto source files of the message types.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"buf.build/go/protoyaml"
+	"buf.build/go/protoyaml/lint"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Server is a language server for the YAML files of a workspace.
+type Server struct {
+	// Config maps the files of the workspace to their message types. Files
+	// that match no pattern are ignored.
+	Config *lint.Config
+	// Root is the directory that the patterns of Config are relative to. If
+	// empty, the root of the workspace given by the client is used.
+	Root string
+	// ProtoRoot is the directory that contains the .proto source files of the
+	// message types, at the paths given by protoreflect.FileDescriptor.Path.
+	// Go-to-definition is only available for types whose descriptors include
+	// source code info. If empty, Root is used.
+	ProtoRoot string
+	// Resolver is used to find the message types of the files, and the types
+	// within them. If it also implements RangeMessages, as
+	// protoregistry.Types does, its message types are offered as completions
+	// of `@type` URLs. If nil, protoregistry.GlobalTypes is used.
+	Resolver interface {
+		protoregistry.MessageTypeResolver
+		protoregistry.ExtensionTypeResolver
+	}
+	// Validator, if set, validates each message after it is unmarshaled.
+	Validator protoyaml.Validator
+}
+
+// Serve reads requests and notifications from r and writes responses and
+// notifications to w, such as the standard input and output of the server,
+// until the client sends the `exit` notification or r is closed.
+//
+// Returns an error if the stream is malformed, or if the client exits without
+// a `shutdown` request.
+func (s Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	session := &session{
+		server:    s,
+		conn:      newConn(r, w),
+		root:      s.Root,
+		documents: make(map[string]*document),
+	}
+	for ctx.Err() == nil {
+		msg, err := session.conn.read()
+		var respErr *responseError
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.As(err, &respErr):
+			if err := session.conn.reply(json.RawMessage("null"), nil, respErr); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return err
+		}
+		if msg.Method == "exit" {
+			if !session.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if err := session.handle(msg); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// session is the state of a connection to a client.
+type session struct {
+	server      Server
+	conn        *conn
+	root        string
+	initialized bool
+	shutdown    bool
+	// The open documents, by URI.
+	documents map[string]*document
+}
+
+// document is an open file.
+type document struct {
+	uri     string
+	version int
+	text    string
+	// The path of the file relative to the root of the workspace, if the file
+	// is in the workspace.
+	path string
+}
+
+// handle handles a request or notification.
+func (s *session) handle(msg *message) error {
+	result, err := s.dispatch(msg)
+	if !msg.isRequest() {
+		return nil // Notifications have no response.
+	}
+	if err == nil && result == nil {
+		result = json.RawMessage("null")
+	}
+	return s.conn.reply(msg.ID, result, err)
+}
+
+// dispatch returns the result of the given message, or an error if it cannot
+// be handled.
+func (s *session) dispatch(msg *message) (any, error) {
+	if msg.Method == "initialize" {
+		return s.initialize(msg.Params)
+	}
+	if !s.initialized {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+	switch msg.Method {
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		return nil, s.didOpen(msg.Params)
+	case "textDocument/didChange":
+		return nil, s.didChange(msg.Params)
+	case "textDocument/didClose":
+		return nil, s.didClose(msg.Params)
+	case "textDocument/completion":
+		return s.handlePosition(msg.Params, (*analysis).complete)
+	case "textDocument/hover":
+		return s.handlePosition(msg.Params, (*analysis).hover)
+	case "textDocument/definition":
+		return s.handlePosition(msg.Params, (*analysis).definition)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+func (s *session) initialize(data json.RawMessage) (any, error) {
+	var params initializeParams
+	if err := unmarshalParams(data, &params); err != nil {
+		return nil, err
+	}
+	if s.root == "" {
+		rootURI := params.RootURI
+		if rootURI == "" && len(params.WorkspaceFolders) > 0 {
+			rootURI = params.WorkspaceFolders[0].URI
+		}
+		s.root, _ = uriToPath(rootURI)
+	}
+	s.initialized = true
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:   1, // Full.
+			CompletionProvider: completionProvider{TriggerCharacters: []string{" "}},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+		},
+		ServerInfo: serverInfo{Name: "protoyaml"},
+	}, nil
+}
+
+func (s *session) didOpen(data json.RawMessage) error {
+	var params didOpenParams
+	if err := unmarshalParams(data, &params); err != nil {
+		return err
+	}
+	return s.update(params.TextDocument)
+}
+
+func (s *session) didChange(data json.RawMessage) error {
+	var params didChangeParams
+	if err := unmarshalParams(data, &params); err != nil {
+		return err
+	}
+	doc := params.TextDocument
+	for _, change := range params.ContentChanges {
+		doc.Text = change.Text // The server only supports full sync.
+	}
+	return s.update(doc)
+}
+
+func (s *session) didClose(data json.RawMessage) error {
+	var params didCloseParams
+	if err := unmarshalParams(data, &params); err != nil {
+		return err
+	}
+	delete(s.documents, params.TextDocument.URI)
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []diagnostic{},
+	})
+}
+
+// handlePosition returns the result of the given analysis at the position of
+// a request, or null if the document is not in the workspace.
+func (s *session) handlePosition(data json.RawMessage, analyze func(*analysis, position) any) (any, error) {
+	var params textDocumentPositionParams
+	if err := unmarshalParams(data, &params); err != nil {
+		return nil, err
+	}
+	doc := s.documents[params.TextDocument.URI]
+	if doc == nil {
+		return nil, nil
+	}
+	msgType, _, err := s.findMessageType(doc)
+	if msgType == nil || err != nil {
+		return nil, nil
+	}
+	result := analyze(s.newAnalysis(doc, msgType.Descriptor()), params.Position)
+	if result == nil {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// update stores the given document and publishes its diagnostics.
+func (s *session) update(item textDocumentItem) error {
+	doc := &document{uri: item.URI, version: item.Version, text: item.Text}
+	if path, ok := uriToPath(item.URI); ok && s.root != "" {
+		if rel, err := filepath.Rel(s.root, path); err == nil && filepath.IsLocal(rel) {
+			doc.path = filepath.ToSlash(rel)
+		}
+	}
+	s.documents[item.URI] = doc
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     doc.version,
+		Diagnostics: s.diagnose(doc),
+	})
+}
+
+// findMessageType returns the message type and config of the given document,
+// or nil if the document is not mapped to a message type.
+func (s *session) findMessageType(doc *document) (protoreflect.MessageType, *lint.FileConfig, error) {
+	if s.server.Config == nil || doc.path == "" {
+		return nil, nil, nil
+	}
+	file := s.server.Config.FindFile(doc.path)
+	if file == nil {
+		return nil, nil, nil
+	}
+	msgType, err := s.resolver().FindMessageByName(protoreflect.FullName(file.Message))
+	if err != nil {
+		return nil, nil, fmt.Errorf("message type %s of pattern %s: %w", file.Message, file.Pattern, err)
+	}
+	return msgType, file, nil
+}
+
+// diagnose returns the errors found in each YAML document of the given file.
+func (s *session) diagnose(doc *document) []diagnostic {
+	result := []diagnostic{}
+	msgType, file, err := s.findMessageType(doc)
+	if err != nil {
+		return append(result, newDiagnostic(lspRange{}, "", err.Error()))
+	}
+	if msgType == nil {
+		return result
+	}
+	options := protoyaml.UnmarshalOptions{
+		Path:           doc.path,
+		Resolver:       s.resolver(),
+		Validator:      s.server.Validator,
+		DiscardUnknown: file.DiscardUnknown,
+		AllowPartial:   file.AllowPartial,
+	}
+	if path, ok := uriToPath(doc.uri); ok {
+		options.IncludeFS = os.DirFS(filepath.Dir(path))
+	}
+	lines := strings.Split(doc.text, "\n")
+	decoder := protoyaml.NewDecoder(strings.NewReader(doc.text), options)
+	for {
+		err := decoder.Decode(msgType.New().Interface())
+		var errs protoyaml.ErrorList
+		switch {
+		case errors.Is(err, io.EOF):
+			return result
+		case errors.As(err, &errs):
+			for _, err := range errs {
+				result = append(result, newErrorDiagnostic(err, doc.path, lines))
+			}
+		case err != nil:
+			return append(result, newDiagnostic(lspRange{}, "", err.Error()))
+		}
+	}
+}
+
+// newErrorDiagnostic returns the diagnostic of the given error. Errors in
+// other files, such as included files, are reported at the start of the file
+// with the location of the error in the message.
+func newErrorDiagnostic(err *protoyaml.Error, path string, lines []string) diagnostic {
+	code := ""
+	var violationErr *protoyaml.ViolationError
+	if errors.As(err.Cause, &violationErr) {
+		code = violationErr.Violation.GetRuleId()
+	}
+	if err.Path != path {
+		return newDiagnostic(lspRange{}, code, fmt.Sprintf("%s:%d:%d %s", err.Path, err.Line, err.Column, err.Cause))
+	}
+	start := newPosition(lines, err.Line, err.Column)
+	end := newPosition(lines, err.EndLine, err.EndColumn)
+	return newDiagnostic(lspRange{Start: start, End: end}, code, err.Cause.Error())
+}
+
+func newDiagnostic(rng lspRange, code string, message string) diagnostic {
+	return diagnostic{
+		Range:    rng,
+		Severity: severityError,
+		Code:     code,
+		Source:   "protoyaml",
+		Message:  message,
+	}
+}
+
+func (s *session) resolver() interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+} {
+	if s.server.Resolver != nil {
+		return s.server.Resolver
+	}
+	return protoregistry.GlobalTypes
+}
+
+func unmarshalParams(data json.RawMessage, params any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// uriToPath returns the local path of the given `file:` URI.
+func uriToPath(uri string) (string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return "", false
+	}
+	path := parsed.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // A Windows drive letter, such as /C:/dir.
+	}
+	return filepath.FromSlash(path), true
+}
+
+// pathToURI returns the `file:` URI of the given local path.
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// newPosition returns the LSP position of the given 1-based line and column,
+// where the column counts characters.
+func newPosition(lines []string, line, column int) position {
+	if line < 1 {
+		return position{}
+	}
+	result := position{Line: line - 1, Character: column - 1}
+	if line <= len(lines) {
+		result.Character = utf16Length(lines[line-1], column-1)
+	}
+	return result
+}
+
+// utf16Length returns the number of UTF-16 code units of the first n
+// characters of the given line.
+func utf16Length(line string, n int) int {
+	length := 0
+	for _, r := range line {
+		if n <= 0 {
+			break
+		}
+		n--
+		length++
+		if r >= 0x10000 {
+			length++ // A surrogate pair.
+		}
+	}
+	return length + max(n, 0)
+}
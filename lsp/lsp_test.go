@@ -0,0 +1,304 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"buf.build/go/protoyaml/lint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	_ "google.golang.org/protobuf/types/known/anypb"
+)
+
+// testFile is the descriptor of the messages used in tests, with source code
+// info for the elements that tests refer to.
+const testFile = `
+name: "acme/config/v1/config.proto"
+package: "acme.config.v1"
+dependency: "google/protobuf/any.proto"
+syntax: "proto3"
+message_type {
+  name: "Config"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "log_level" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".acme.config.v1.Level" json_name: "logLevel" }
+  field { name: "items" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".acme.config.v1.Item" json_name: "items" }
+  field { name: "extra" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Any" json_name: "extra" }
+  field { name: "url" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "url" }
+  field { name: "path" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "path" }
+  field { name: "enabled" number: 7 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "enabled" }
+  oneof_decl { name: "source" }
+}
+message_type {
+  name: "Item"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "id" }
+  field { name: "levels" number: 2 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".acme.config.v1.Level" json_name: "levels" }
+}
+enum_type {
+  name: "Level"
+  value { name: "LEVEL_UNSPECIFIED" number: 0 }
+  value { name: "LEVEL_LOW" number: 1 }
+  value { name: "LEVEL_HIGH" number: 2 }
+}
+source_code_info {
+  location { path: [4, 0] span: [4, 0, 12, 1] leading_comments: " The configuration of a service.\n" }
+  location { path: [4, 0, 2, 0] span: [6, 2, 18] leading_comments: " The name of the service.\n Must be unique.\n" }
+  location { path: [4, 0, 2, 1] span: [7, 2, 21] trailing_comments: " The minimum level to log.\n" }
+  location { path: [4, 1] span: [14, 0, 17, 1] leading_comments: " An item.\n" }
+  location { path: [5, 0, 2, 2] span: [22, 2, 17] leading_comments: " Only important messages.\n" }
+}
+`
+
+// newTestTypes returns the types of testFile.
+func newTestTypes(t *testing.T) *protoregistry.Types {
+	t.Helper()
+	fileProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(testFile), fileProto))
+	file, err := protodesc.NewFile(fileProto, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	types := &protoregistry.Types{}
+	for i := range file.Messages().Len() {
+		require.NoError(t, types.RegisterMessage(dynamicpb.NewMessageType(file.Messages().Get(i))))
+	}
+	return types
+}
+
+// newTestServer returns a server that maps `*.yaml` files to
+// acme.config.v1.Config.
+func newTestServer(t *testing.T) Server {
+	t.Helper()
+	config, err := lint.ParseConfig([]byte("files:\n  - pattern: '*.yaml'\n    message: acme.config.v1.Config\n"))
+	require.NoError(t, err)
+	return Server{Config: config, Resolver: newTestTypes(t)}
+}
+
+// testClient talks to a server over a pair of pipes, as over stdio.
+type testClient struct {
+	t        *testing.T
+	root     string
+	conn     *conn
+	writer   *io.PipeWriter
+	messages chan *message
+	done     chan error
+	nextID   int
+}
+
+func newTestClient(t *testing.T, server Server) *testClient {
+	t.Helper()
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	client := &testClient{
+		t:        t,
+		root:     t.TempDir(),
+		conn:     newConn(clientReader, clientWriter),
+		writer:   clientWriter,
+		messages: make(chan *message, 100),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		client.done <- server.Serve(context.Background(), serverReader, serverWriter)
+		_ = serverWriter.Close()
+	}()
+	go func() {
+		defer close(client.messages)
+		for {
+			msg, err := client.conn.read()
+			if err != nil {
+				return
+			}
+			client.messages <- msg
+		}
+	}()
+	t.Cleanup(func() { _ = clientWriter.Close() })
+	return client
+}
+
+// initialize sends the initialize request with the client's root.
+func (c *testClient) initialize() initializeResult {
+	c.t.Helper()
+	var result initializeResult
+	require.NoError(c.t, c.request("initialize", initializeParams{RootURI: pathToURI(c.root)}, &result))
+	c.notify("initialized", struct{}{})
+	return result
+}
+
+// request sends a request and decodes the result of its response.
+func (c *testClient) request(method string, params any, result any) error {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	data, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.conn.write(&message{ID: id, Method: method, Params: data}))
+	for msg := range c.messages {
+		if string(msg.ID) != string(id) {
+			continue // A notification.
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		require.NoError(c.t, json.Unmarshal(msg.Result, result))
+		return nil
+	}
+	c.t.Fatalf("no response to %s", method)
+	return nil
+}
+
+// notify sends a notification.
+func (c *testClient) notify(method string, params any) {
+	c.t.Helper()
+	data, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.conn.write(&message{Method: method, Params: data}))
+}
+
+// diagnostics returns the next diagnostics published by the server.
+func (c *testClient) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+	for msg := range c.messages {
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params publishDiagnosticsParams
+			require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+			return params
+		}
+	}
+	c.t.Fatal("no diagnostics")
+	return publishDiagnosticsParams{}
+}
+
+// open opens a document with the given name and text, and returns its URI
+// after its diagnostics are published.
+func (c *testClient) open(name string, text string) string {
+	c.t.Helper()
+	uri := pathToURI(filepath.Join(c.root, name))
+	c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: uri, Version: 1, Text: text}})
+	assert.Equal(c.t, uri, c.diagnostics().URI)
+	return uri
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	var result any
+	err := client.request("textDocument/hover", textDocumentPositionParams{}, &result)
+	var respErr *responseError
+	require.ErrorAs(t, err, &respErr)
+	assert.Equal(t, codeServerNotInitialized, respErr.Code)
+
+	initResult := client.initialize()
+	assert.Equal(t, 1, initResult.Capabilities.TextDocumentSync)
+	assert.True(t, initResult.Capabilities.HoverProvider)
+	assert.True(t, initResult.Capabilities.DefinitionProvider)
+
+	err = client.request("workspace/symbol", struct{}{}, &result)
+	require.ErrorAs(t, err, &respErr)
+	assert.Equal(t, codeMethodNotFound, respErr.Code)
+	require.NoError(t, client.request("shutdown", nil, &result))
+	assert.Nil(t, result)
+	client.notify("exit", nil)
+	require.NoError(t, <-client.done)
+
+	client = newTestClient(t, newTestServer(t))
+	client.initialize()
+	client.notify("exit", nil)
+	require.EqualError(t, <-client.done, "exit without shutdown")
+}
+
+func TestDiagnostics(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t, newTestServer(t))
+	client.initialize()
+
+	uri := pathToURI(filepath.Join(client.root, "config.yaml"))
+	client.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{
+		URI:     uri,
+		Version: 1,
+		Text:    "name: \"😀\"\nlogLevel: LEVEL_HGH\n---\nitems:\n  - id: x\n",
+	}})
+	params := client.diagnostics()
+	assert.Equal(t, uri, params.URI)
+	assert.Equal(t, 1, params.Version)
+	require.Len(t, params.Diagnostics, 2)
+	assert.Equal(t, lspRange{Start: position{Line: 1, Character: 10}, End: position{Line: 1, Character: 19}}, params.Diagnostics[0].Range)
+	assert.Contains(t, params.Diagnostics[0].Message, `did you mean "LEVEL_HIGH"?`)
+	assert.Equal(t, "protoyaml", params.Diagnostics[0].Source)
+	assert.Equal(t, severityError, params.Diagnostics[0].Severity)
+	assert.Equal(t, lspRange{Start: position{Line: 4, Character: 8}, End: position{Line: 4, Character: 9}}, params.Diagnostics[1].Range)
+
+	client.notify("textDocument/didChange", didChangeParams{
+		TextDocument: textDocumentItem{URI: uri, Version: 2},
+		ContentChanges: []struct {
+			Text string `json:"text"`
+		}{{Text: "name: \"😀\" # Smile.\nurl: a\npath: b\n"}},
+	})
+	params = client.diagnostics()
+	assert.Equal(t, 2, params.Version)
+	require.Len(t, params.Diagnostics, 1)
+	assert.Equal(t, lspRange{Start: position{Line: 2, Character: 6}, End: position{Line: 2, Character: 7}}, params.Diagnostics[0].Range)
+
+	client.notify("textDocument/didClose", didCloseParams{TextDocument: textDocumentIdentifier{URI: uri}})
+	assert.Empty(t, client.diagnostics().Diagnostics)
+
+	// Files that match no pattern are ignored.
+	client.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{
+		URI:  pathToURI(filepath.Join(client.root, "sub", "other.yaml")),
+		Text: "unknown: 1\n",
+	}})
+	assert.Empty(t, client.diagnostics().Diagnostics)
+
+	// Errors in the config are reported at the start of the file.
+	server := newTestServer(t)
+	server.Config.Files[0].Message = "acme.config.v1.Missing"
+	client = newTestClient(t, server)
+	client.initialize()
+	client.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{
+		URI:  pathToURI(filepath.Join(client.root, "config.yaml")),
+		Text: "name: a\n",
+	}})
+	params = client.diagnostics()
+	require.Len(t, params.Diagnostics, 1)
+	assert.Contains(t, params.Diagnostics[0].Message, "message type acme.config.v1.Missing of pattern *.yaml")
+}
+
+func TestPositions(t *testing.T) {
+	t.Parallel()
+	lines := []string{"a: 😀b", ""}
+	assert.Equal(t, position{Line: 0, Character: 5}, newPosition(lines, 1, 5))
+	assert.Equal(t, position{Line: 0, Character: 6}, newPosition(lines, 1, 6))
+	assert.Equal(t, position{Line: 1, Character: 2}, newPosition(lines, 2, 3))
+	assert.Equal(t, position{}, newPosition(lines, 0, 0))
+	assert.Equal(t, len("a: 😀"), byteOffset(lines[0], 5))
+	assert.Equal(t, len(lines[0]), byteOffset(lines[0], 10))
+	path := filepath.Join(string(filepath.Separator)+"dir", "a b.yaml")
+	uri := pathToURI(path)
+	assert.True(t, strings.HasPrefix(uri, "file:///"), uri)
+	decoded, ok := uriToPath(uri)
+	assert.True(t, ok)
+	assert.Equal(t, path, decoded)
+	_, ok = uriToPath("untitled:Untitled-1")
+	assert.False(t, ok)
+}
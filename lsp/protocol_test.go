@@ -0,0 +1,56 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+	var output bytes.Buffer
+	input := "Content-Length: 46\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n" +
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}` +
+		"Content-Length: 5\r\n\r\n{bad}"
+	c := newConn(strings.NewReader(input), &output)
+	msg, err := c.read()
+	require.NoError(t, err)
+	assert.Equal(t, json.RawMessage("1"), msg.ID)
+	assert.Equal(t, "initialize", msg.Method)
+	assert.True(t, msg.isRequest())
+	_, err = c.read()
+	var respErr *responseError
+	require.ErrorAs(t, err, &respErr)
+	assert.Equal(t, codeParseError, respErr.Code)
+	_, err = c.read()
+	require.ErrorIs(t, err, io.EOF)
+
+	require.NoError(t, c.reply(msg.ID, nil, nil))
+	require.NoError(t, c.reply(json.RawMessage(`"a"`), nil, &responseError{Code: codeMethodNotFound, Message: "not found"}))
+	require.NoError(t, c.notify("window/logMessage", map[string]any{"type": 3}))
+	assert.Equal(t, "Content-Length: 38\r\n\r\n"+`{"jsonrpc":"2.0","id":1,"result":null}`+
+		"Content-Length: 72\r\n\r\n"+`{"jsonrpc":"2.0","id":"a","error":{"code":-32601,"message":"not found"}}`+
+		"Content-Length: 66\r\n\r\n"+`{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":3}}`, output.String())
+
+	_, err = newConn(strings.NewReader("Content-Length: x\r\n\r\n"), &output).read()
+	require.ErrorContains(t, err, `invalid Content-Length "x"`)
+}
@@ -0,0 +1,106 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// redactFields replaces the values of the redacted fields in the given message
+// node with a placeholder.
+func (o MarshalOptions) redactFields(node *yaml.Node, msgDesc protoreflect.MessageDescriptor) {
+	if node.Kind != yaml.MappingNode || isSpecialMessage(msgDesc) {
+		return // A well-known type with a special representation.
+	}
+	if msgDesc.FullName() == "google.protobuf.Any" {
+		packed := o.findAnyMessage(node)
+		switch {
+		case packed == nil:
+			return
+		case packed.FullName() == "google.protobuf.Any":
+			// A packed Any is in the `value` entry, as other well-known types.
+			if _, value, ok := findEntryByKey(node, "value"); ok {
+				o.redactFields(value, packed)
+			}
+			return
+		}
+		msgDesc = packed
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		field := o.findMarshaledField(node.Content[i].Value, msgDesc)
+		if field == nil {
+			continue
+		}
+		node.Content[i+1] = o.redactValue(node.Content[i+1], field)
+	}
+}
+
+// redactValue returns the given value of the given field, with a placeholder
+// if the field is redacted, or with the redacted fields of its messages
+// replaced.
+func (o MarshalOptions) redactValue(node *yaml.Node, field protoreflect.FieldDescriptor) *yaml.Node {
+	switch {
+	case o.isRedacted(field):
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redactedText}
+	case field.IsList() && field.Message() != nil:
+		for _, item := range node.Content {
+			o.redactFields(item, field.Message())
+		}
+	case field.IsMap() && field.MapValue().Message() != nil:
+		for i := 1; i < len(node.Content); i += 2 {
+			o.redactFields(node.Content[i], field.MapValue().Message())
+		}
+	case field.Message() != nil && !field.IsMap():
+		o.redactFields(node, field.Message())
+	}
+	return node
+}
+
+// isSpecialMessage returns true if the given message is a well-known type with
+// a special representation, other than google.protobuf.Any. The keys of its
+// node, such as those of a google.protobuf.Struct, are not field names.
+func isSpecialMessage(msgDesc protoreflect.MessageDescriptor) bool {
+	return msgDesc.FullName() != "google.protobuf.Any" && findWKTMarshaler(msgDesc.FullName()) != nil
+}
+
+// isRedacted returns true if the value of the given field should be redacted.
+func (o MarshalOptions) isRedacted(field protoreflect.FieldDescriptor) bool {
+	if options, ok := field.Options().(*descriptorpb.FieldOptions); ok && options.GetDebugRedact() {
+		return true
+	}
+	return o.RedactField != nil && o.RedactField(field)
+}
+
+// findAnyMessage returns the descriptor of the message in the given
+// google.protobuf.Any node, or nil if it is unknown or is a well-known type
+// with a special representation, other than google.protobuf.Any itself.
+func (o MarshalOptions) findAnyMessage(node *yaml.Node) protoreflect.MessageDescriptor {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != atTypeFieldName {
+			continue
+		}
+		msgType, err := o.getResolver().FindMessageByURL(node.Content[i+1].Value)
+		if err != nil {
+			return nil
+		}
+		if _, ok := wktUnmarshalers[msgType.Descriptor().FullName()]; ok && msgType.Descriptor().FullName() != "google.protobuf.Any" {
+			return nil
+		}
+		return msgType.Descriptor()
+	}
+	return nil
+}
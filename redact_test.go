@@ -0,0 +1,179 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+
+	"buf.build/go/protoyaml/internal/gen/proto/bufext/cel/expr/conformance/proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// newRedactTestType returns a message type with a debug_redact field, and a
+// resolver that includes it.
+func newRedactTestType(t *testing.T) (protoreflect.MessageType, *protoregistry.Types) {
+	t.Helper()
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		result := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  label.Enum(),
+			Type:   fieldType.Enum(),
+		}
+		if typeName != "" {
+			result.TypeName = proto.String(typeName)
+		}
+		return result
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	messageType := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	password := field("password", 2, optional, stringType, "")
+	password.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("redact.proto"),
+		Package:    proto.String("test.redact"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Config"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, optional, stringType, ""),
+				password,
+				field("children", 3, repeated, messageType, ".test.redact.Config"),
+				field("by_name", 4, repeated, messageType, ".test.redact.Config.ByNameEntry"),
+				field("payload", 5, optional, messageType, ".google.protobuf.Any"),
+				field("token", 6, optional, stringType, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("ByNameEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("key", 1, optional, stringType, ""),
+					field("value", 2, optional, messageType, ".test.redact.Config"),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	msgType := dynamicpb.NewMessageType(file.Messages().Get(0))
+	types := &protoregistry.Types{}
+	require.NoError(t, types.RegisterMessage(msgType))
+	return msgType, types
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+	msgType, types := newRedactTestType(t)
+	message := msgType.New().Interface()
+	data := []byte(`name: root
+password: a
+children:
+  - name: child
+    password: b
+by_name:
+  x: {password: c, token: d}
+payload:
+  "@type": type.googleapis.com/test.redact.Config
+  password: e
+token: f
+`)
+	require.NoError(t, UnmarshalOptions{Resolver: types}.Unmarshal(data, message))
+
+	output, err := MarshalOptions{Indent: 2, Resolver: types, Redact: true}.Marshal(message)
+	require.NoError(t, err)
+	assert.Equal(t, `name: root
+password: '[REDACTED]'
+children:
+  - name: child
+    password: '[REDACTED]'
+byName:
+  x:
+    password: '[REDACTED]'
+    token: d
+payload:
+  '@type': type.googleapis.com/test.redact.Config
+  password: '[REDACTED]'
+token: f
+`, string(output))
+
+	// Fields can also be redacted by a predicate.
+	output, err = MarshalOptions{
+		Resolver:      types,
+		UseProtoNames: true,
+		Redact:        true,
+		RedactField: func(field protoreflect.FieldDescriptor) bool {
+			return field.Name() == "token" || field.Name() == "children"
+		},
+	}.Marshal(message)
+	require.NoError(t, err)
+	assert.NotContains(t, string(output), "name: child")
+	assert.Contains(t, string(output), "children: '[REDACTED]'\n")
+	assert.Contains(t, string(output), "token: '[REDACTED]'\n")
+
+	// Fields are not redacted by default.
+	output, err = MarshalOptions{Resolver: types}.Marshal(message)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "password: a\n")
+}
+
+func TestRedactNestedAny(t *testing.T) {
+	t.Parallel()
+	msgType, types := newRedactTestType(t)
+	require.NoError(t, types.RegisterMessage((&anypb.Any{}).ProtoReflect().Type()))
+	message := msgType.New().Interface()
+	data := []byte(`payload:
+  "@type": type.googleapis.com/google.protobuf.Any
+  value:
+    "@type": type.googleapis.com/test.redact.Config
+    password: a
+`)
+	require.NoError(t, UnmarshalOptions{Resolver: types}.Unmarshal(data, message))
+
+	output, err := MarshalOptions{Indent: 2, Resolver: types, Redact: true}.Marshal(message)
+	require.NoError(t, err)
+	assert.Equal(t, `payload:
+  '@type': type.googleapis.com/google.protobuf.Any
+  value:
+    '@type': type.googleapis.com/test.redact.Config
+    password: '[REDACTED]'
+`, string(output))
+}
+
+func TestRedactStruct(t *testing.T) {
+	t.Parallel()
+	// The keys of a Struct are not field names, even if they match one.
+	message := &proto3.TestAllTypes{}
+	require.NoError(t, Unmarshal([]byte("single_struct: {fields: kept, password: kept}\n"), message))
+	options := MarshalOptions{
+		Indent: 2,
+		Redact: true,
+		RedactField: func(field protoreflect.FieldDescriptor) bool {
+			return field.Name() == "fields" || field.Name() == "string_value"
+		},
+	}
+	output, err := options.Marshal(message)
+	require.NoError(t, err)
+	assert.Equal(t, "singleStruct:\n  fields: kept\n  password: kept\n", string(output))
+}
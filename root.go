@@ -0,0 +1,55 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"strconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// findRoot returns the node at UnmarshalOptions.RootPath in the given document
+// root, or reports an error and returns nil if there is no such node. Includes
+// and aliases must already be resolved.
+func (u *unmarshaler) findRoot(node *yaml.Node) *yaml.Node {
+	rootPath := u.options.RootPath
+	keys, err := parseFieldPath(rootPath)
+	if err != nil {
+		u.addErrorf(node, "invalid root path %#v", rootPath)
+		return nil
+	}
+	for _, key := range keys {
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, value, ok := findEntryByKey(node, key)
+			if !ok {
+				u.addErrorf(node, "root path %#v not found: no key %#v", rootPath, key)
+				return nil
+			}
+			node = value
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				u.addErrorf(node, "root path %#v not found: no index %s in sequence of %d items", rootPath, key, len(node.Content))
+				return nil
+			}
+			node = node.Content[index]
+		default:
+			u.addErrorf(node, "root path %#v not found: expected mapping or sequence for %#v, got %v", rootPath, key, getNodeKind(node.Kind))
+			return nil
+		}
+	}
+	return node
+}
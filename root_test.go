@@ -0,0 +1,124 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+	"testing/fstest"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rootPathManifest = `apiVersion: v1
+kind: Service
+defaults: &defaults
+  values:
+    - oneof_string_value: default
+spec:
+  config:
+    values:
+      - oneof_string_value: hi
+  items:
+    - name: first
+      config: *defaults
+`
+
+func TestRootPath(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Name     string
+		RootPath string
+		Expected string
+	}{
+		{Name: "Mapping", RootPath: "spec.config", Expected: "hi"},
+		{Name: "SequenceAndAlias", RootPath: "spec.items[0].config", Expected: "default"},
+		{Name: "QuotedKey", RootPath: `spec["config"]`, Expected: "hi"},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			actual := &testv1.Proto2Test{}
+			err := UnmarshalOptions{RootPath: testCase.RootPath}.Unmarshal([]byte(rootPathManifest), actual)
+			require.NoError(t, err)
+			require.Len(t, actual.GetValues(), 1)
+			assert.Equal(t, testCase.Expected, actual.GetValues()[0].GetOneofStringValue())
+		})
+	}
+}
+
+func TestRootPathError(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		Name     string
+		RootPath string
+		Error    string
+	}{
+		{
+			Name:     "FieldError",
+			RootPath: "spec",
+			Error:    "test.yaml:7:3 unknown field \"config\", expected one of [values]\n   7 |   config:\n   7 | ..^\n",
+		},
+		{
+			Name:     "MissingKey",
+			RootPath: "spec.settings",
+			Error:    "test.yaml:7:3 root path \"spec.settings\" not found: no key \"settings\"\n",
+		},
+		{
+			Name:     "MissingIndex",
+			RootPath: "spec.items[1].config",
+			Error:    "test.yaml:11:5 root path \"spec.items[1].config\" not found: no index 1 in sequence of 1 items\n",
+		},
+		{
+			Name:     "Scalar",
+			RootPath: "kind.config",
+			Error:    "test.yaml:2:7 root path \"kind.config\" not found: expected mapping or sequence for \"config\", got scalar\n",
+		},
+		{
+			Name:     "Invalid",
+			RootPath: "spec[0]x",
+			Error:    "test.yaml:1:1 invalid root path \"spec[0]x\"\n",
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			options := UnmarshalOptions{Path: "test.yaml", RootPath: testCase.RootPath}
+			err := options.Unmarshal([]byte(rootPathManifest), &testv1.Proto2Test{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+}
+
+func TestRootPathInclude(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{
+		RootPath: "spec.config",
+		IncludeFS: fstest.MapFS{
+			"spec.yaml": {Data: []byte("config:\n  values:\n    - oneof_string_value: included\n")},
+		},
+	}
+	actual := &testv1.Proto2Test{}
+	require.NoError(t, options.Unmarshal([]byte("spec: !include spec.yaml\n"), actual))
+	require.Len(t, actual.GetValues(), 1)
+	assert.Equal(t, "included", actual.GetValues()[0].GetOneofStringValue())
+}
+
+func TestRootPathEmpty(t *testing.T) {
+	t.Parallel()
+	err := UnmarshalOptions{Path: "test.yaml", RootPath: "spec.config"}.Unmarshal(nil, &testv1.Proto2Test{})
+	require.Error(t, err)
+	assert.Equal(t, "test.yaml:1:1 root path \"spec.config\" not found: the document is empty\n", err.Error())
+}
@@ -0,0 +1,315 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	secretTag = "!secret"
+	// The text that replaces secret values in error messages.
+	redactedText = "[REDACTED]"
+	// The minimum length of the secret values that are redacted from error
+	// messages. Shorter values would match unrelated text.
+	minRedactedLength = 4
+)
+
+// SecretResolver resolves the references of `!secret` tags to secret values.
+type SecretResolver interface {
+	// ResolveSecret returns the value of the secret with the given reference.
+	ResolveSecret(reference string) ([]byte, error)
+}
+
+// SecretMap is a SecretResolver that resolves references to the values in the
+// map. It is mainly useful for tests.
+type SecretMap map[string]string
+
+// ResolveSecret returns the value for the given reference in the map.
+func (m SecretMap) ResolveSecret(reference string) ([]byte, error) {
+	value, ok := m[reference]
+	if !ok {
+		return nil, fmt.Errorf("secret %#v not found", reference)
+	}
+	return []byte(value), nil
+}
+
+// NewFileSecretResolver returns a SecretResolver that resolves references to
+// the contents of the files with those names in the given file system, such as
+// a directory of mounted secrets. A single trailing newline is removed.
+func NewFileSecretResolver(fsys fs.FS) SecretResolver {
+	return &fileSecretResolver{fsys: fsys}
+}
+
+type fileSecretResolver struct {
+	fsys fs.FS
+}
+
+func (r *fileSecretResolver) ResolveSecret(reference string) ([]byte, error) {
+	data, err := fs.ReadFile(r.fsys, reference)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	return bytes.TrimSuffix(data, []byte{'\r'}), nil
+}
+
+// SecretReferences records the references of the secrets resolved while
+// unmarshaling, so that marshaling can emit the references in place of the
+// secret values.
+//
+// The zero value is ready to use.
+type SecretReferences struct {
+	byFieldPath map[string]*secretReference
+}
+
+type secretReference struct {
+	reference string
+	value     []byte
+}
+
+// Lookup returns the reference of the secret resolved for the field at the
+// given path, such as `values[2].password`, if any.
+func (r *SecretReferences) Lookup(fieldPath string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	ref, ok := r.byFieldPath[fieldPath]
+	if !ok {
+		return "", false
+	}
+	return ref.reference, true
+}
+
+func (r *SecretReferences) add(fieldPath string, reference string, value []byte) {
+	if r.byFieldPath == nil {
+		r.byFieldPath = make(map[string]*secretReference)
+	}
+	r.byFieldPath[fieldPath] = &secretReference{reference: reference, value: value}
+}
+
+// resolveSecret returns a node with the value of the secret referenced by the
+// given `!secret` node, as the value of the given field.
+//
+// Returns nil if the secret could not be resolved.
+func (u *unmarshaler) resolveSecret(node *yaml.Node, field protoreflect.FieldDescriptor) *yaml.Node {
+	if !u.checkKind(node, yaml.ScalarNode) {
+		return nil
+	}
+	kind := field.Kind()
+	if kind != protoreflect.StringKind && kind != protoreflect.BytesKind {
+		u.addErrorf(node, "%s can only be used for string and bytes fields, got %v", secretTag, kind)
+		return nil
+	}
+	value, err := u.options.SecretResolver.ResolveSecret(node.Value)
+	if err != nil {
+		u.addErrorf(node, "failed to resolve secret %#v: %w", node.Value, err)
+		return nil
+	}
+	if len(value) >= minRedactedLength {
+		u.secrets = append(u.secrets, string(value), base64.StdEncoding.EncodeToString(value))
+	}
+	result := u.copyNode(node)
+	result.Style = 0
+	if kind == protoreflect.StringKind {
+		if !utf8.Valid(value) {
+			u.addErrorf(node, "secret %#v is not valid UTF-8", node.Value)
+			return nil
+		}
+		result.Tag = "!!str"
+		result.Value = string(value)
+	} else {
+		result.Tag = "!!binary"
+		result.Value = base64.StdEncoding.EncodeToString(value)
+	}
+	if u.options.SecretReferences != nil {
+		u.options.SecretReferences.add(strings.Join(u.fieldPath, ""), node.Value, value)
+	}
+	return result
+}
+
+// redactSecrets replaces the resolved secret values in the causes of the given
+// errors.
+func (u *unmarshaler) redactSecrets(errs []*Error) {
+	if len(u.secrets) == 0 {
+		return
+	}
+	for _, err := range errs {
+		var violationErr *ViolationError
+		if errors.As(err.Cause, &violationErr) {
+			message := violationErr.Violation.GetMessage()
+			if redacted := u.redact(message); redacted != message {
+				violation := proto.CloneOf(violationErr.Violation)
+				violation.SetMessage(redacted)
+				err.Cause = &ViolationError{Violation: violation}
+			}
+			continue
+		}
+		message := err.Cause.Error()
+		if redacted := u.redact(message); redacted != message {
+			err.Cause = errors.New(redacted)
+		}
+	}
+}
+
+func (u *unmarshaler) redact(text string) string {
+	for _, secret := range u.secrets {
+		text = replaceToken(text, secret, redactedText)
+	}
+	return text
+}
+
+// replaceToken replaces the occurrences of the given token in the text that
+// are not part of a longer word.
+func replaceToken(text, token, replacement string) string {
+	var result strings.Builder
+	for {
+		index := strings.Index(text, token)
+		if index < 0 {
+			break
+		}
+		end := index + len(token)
+		startsWord := index == 0 || !isWordByte(text[index-1]) || !isWordByte(token[0])
+		endsWord := end == len(text) || !isWordByte(text[end]) || !isWordByte(token[len(token)-1])
+		if startsWord && endsWord {
+			result.WriteString(text[:index])
+			result.WriteString(replacement)
+			text = text[end:]
+		} else {
+			result.WriteString(text[:index+1])
+			text = text[index+1:]
+		}
+	}
+	result.WriteString(text)
+	return result.String()
+}
+
+// isWordByte returns true if the given byte may be part of a word.
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// referenceResolver is a SecretResolver that resolves references to the
+// secret values recorded by SecretReferences.
+type referenceResolver struct {
+	references *SecretReferences
+}
+
+func (r referenceResolver) ResolveSecret(reference string) ([]byte, error) {
+	for _, ref := range r.references.byFieldPath {
+		if ref.reference == reference {
+			return ref.value, nil
+		}
+	}
+	return nil, fmt.Errorf("secret %#v not recorded", reference)
+}
+
+// replaceSecrets replaces the values of the given message node that were
+// resolved from secrets with `!secret` references to them.
+//
+// The path is the field path of the node, as recorded by SecretReferences.
+func (o MarshalOptions) replaceSecrets(node *yaml.Node, msgDesc protoreflect.MessageDescriptor, path string) {
+	if node.Kind != yaml.MappingNode || isSpecialMessage(msgDesc) {
+		return // A well-known type with a special representation.
+	}
+	if msgDesc.FullName() == "google.protobuf.Any" {
+		if msgDesc = o.findAnyMessage(node); msgDesc == nil {
+			return
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		field := o.findMarshaledField(node.Content[i].Value, msgDesc)
+		if field == nil {
+			continue
+		}
+		fieldPath := getFieldPathName(field)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		o.replaceValueSecrets(node.Content[i+1], field, fieldPath)
+	}
+}
+
+// replaceValueSecrets replaces the secrets in the given value of the given
+// field, which may be a list or a map.
+func (o MarshalOptions) replaceValueSecrets(node *yaml.Node, field protoreflect.FieldDescriptor, path string) {
+	switch {
+	case field.IsList() && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			o.replaceFieldSecrets(item, field, path+"["+strconv.Itoa(i)+"]")
+		}
+	case field.IsMap() && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if field.MapKey().Kind() == protoreflect.StringKind {
+				key = strconv.Quote(key)
+			}
+			o.replaceFieldSecrets(node.Content[i+1], field.MapValue(), path+"["+key+"]")
+		}
+	default:
+		o.replaceFieldSecrets(node, field, path)
+	}
+}
+
+// replaceFieldSecrets replaces the given value of the given field with a
+// `!secret` reference, if it was resolved from a secret.
+func (o MarshalOptions) replaceFieldSecrets(node *yaml.Node, field protoreflect.FieldDescriptor, path string) {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		o.replaceSecrets(node, field.Message(), path)
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		ref, ok := o.SecretReferences.byFieldPath[path]
+		if !ok || node.Kind != yaml.ScalarNode {
+			return
+		}
+		value := string(ref.value)
+		if field.Kind() == protoreflect.BytesKind {
+			value = base64.StdEncoding.EncodeToString(ref.value)
+		}
+		if node.Value == value {
+			node.Tag = secretTag
+			node.Value = ref.reference
+			node.Style = 0
+		}
+	default:
+	}
+}
+
+// findMarshaledField returns the field with the given name in the output, or
+// nil if there is no such field.
+func (o MarshalOptions) findMarshaledField(name string, msgDesc protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		extType, err := o.getResolver().FindExtensionByName(protoreflect.FullName(name[1 : len(name)-1]))
+		if err != nil {
+			return nil
+		}
+		return extType.TypeDescriptor()
+	}
+	if o.UseProtoNames {
+		return msgDesc.Fields().ByTextName(name)
+	}
+	return msgDesc.Fields().ByJSONName(name)
+}
@@ -0,0 +1,144 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+var testSecrets = SecretMap{
+	"db/password": "hunter2",
+	"api/key":     "\x00\x01secret",
+}
+
+type secretLeakingValidator struct{}
+
+func (secretLeakingValidator) Validate(message proto.Message) error {
+	if test, ok := message.(*testv1.Proto3Test); ok && len(test.GetValues()) > 0 {
+		return fmt.Errorf("password %q is too weak", test.GetValues()[0].GetSingleString())
+	}
+	return nil
+}
+
+func TestSecrets(t *testing.T) {
+	t.Parallel()
+	references := &SecretReferences{}
+	options := UnmarshalOptions{SecretResolver: testSecrets, SecretReferences: references}
+	data := []byte(`values:
+  - single_string: !secret db/password
+    single_bytes: !secret api/key
+    repeated_string: [a, !secret db/password]
+    map_string_string: {a: !secret db/password}
+`)
+	actual := &testv1.Proto3Test{}
+	require.NoError(t, options.Unmarshal(data, actual))
+	value := actual.GetValues()[0]
+	assert.Equal(t, "hunter2", value.GetSingleString())
+	assert.Equal(t, []byte("\x00\x01secret"), value.GetSingleBytes())
+	assert.Equal(t, []string{"a", "hunter2"}, value.GetRepeatedString())
+	assert.Equal(t, map[string]string{"a": "hunter2"}, value.GetMapStringString())
+	ref, ok := references.Lookup(`values[0].map_string_string["a"]`)
+	assert.True(t, ok)
+	assert.Equal(t, "db/password", ref)
+
+	// Marshaling emits the references instead of the values.
+	output, err := MarshalOptions{Indent: 2, SecretReferences: references}.Marshal(actual)
+	require.NoError(t, err)
+	assert.NotContains(t, string(output), "hunter2")
+	assert.Equal(t, `values:
+  - singleString: !secret db/password
+    singleBytes: !secret api/key
+    repeatedString:
+      - a
+      - !secret db/password
+    mapStringString:
+      a: !secret db/password
+`, string(output))
+	roundTrip := &testv1.Proto3Test{}
+	require.NoError(t, options.Unmarshal(output, roundTrip))
+	assert.True(t, proto.Equal(actual, roundTrip))
+
+	// Changed values are emitted as is.
+	value.SingleString = "changed"
+	output, err = MarshalOptions{SecretReferences: references, UseProtoNames: true}.Marshal(actual)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "single_string: changed\n")
+	assert.Contains(t, string(output), "single_bytes: !secret api/key\n")
+}
+
+func TestSecretErrors(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{
+		Path:           "test.yaml",
+		SecretResolver: testSecrets,
+		Validator:      secretLeakingValidator{},
+	}
+	err := options.Unmarshal([]byte("values:\n  - single_string: !secret db/password\n"), &testv1.Proto3Test{})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "hunter2")
+	assert.Contains(t, err.Error(), `password "[REDACTED]" is too weak`)
+
+	data := []byte(`values:
+  - single_int32: !secret db/password
+    single_string: !secret missing
+    single_bool: true
+`)
+	options.Validator = nil
+	err = options.Unmarshal(data, &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 2)
+	assert.Equal(t, "!secret can only be used for string and bytes fields, got int32", errList[0].Cause.Error())
+	assert.Equal(t, `failed to resolve secret "missing": secret "missing" not found`, errList[1].Cause.Error())
+	assert.Equal(t, 3, errList[1].Line)
+	assert.Equal(t, 20, errList[1].Column)
+}
+
+func TestSecretErrorsShortSecret(t *testing.T) {
+	t.Parallel()
+	// Secrets that are too short to tell from other text are not redacted.
+	options := UnmarshalOptions{
+		SecretResolver: SecretMap{"short": "e"},
+		Validator:      secretLeakingValidator{},
+	}
+	err := options.Unmarshal([]byte("values:\n  - single_string: !secret short\n"), &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 1)
+	assert.Equal(t, `password "e" is too weak`, errList[0].Cause.Error())
+
+	// Only whole words are redacted.
+	assert.Equal(t, "[REDACTED] hunter2x [REDACTED].", replaceToken("hunter2 hunter2x hunter2.", "hunter2", redactedText))
+	assert.Equal(t, "a[REDACTED] b", replaceToken("a\x00secret b", "\x00secret", redactedText))
+}
+
+func TestFileSecretResolver(t *testing.T) {
+	t.Parallel()
+	resolver := NewFileSecretResolver(fstest.MapFS{
+		"db/password": {Data: []byte("hunter2\n")},
+	})
+	value, err := resolver.ResolveSecret("db/password")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hunter2"), value)
+	_, err = resolver.ResolveSecret("missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,285 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// Decoder reads a stream of `---` separated YAML documents, unmarshaling each
+// document into a Protobuf message.
+type Decoder struct {
+	options UnmarshalOptions
+	reader  *bufio.Reader
+	// The number of lines consumed from the reader.
+	lineCount int
+	// A document start marker that was read ahead, if any.
+	pending []byte
+	err     error
+}
+
+// NewDecoder returns a new Decoder that reads from r using the given options.
+//
+// The Decoder only buffers a single document at a time. Errors report line
+// numbers relative to the start of the stream.
+func NewDecoder(r io.Reader, options UnmarshalOptions) *Decoder {
+	return &Decoder{
+		options: options,
+		reader:  bufio.NewReader(r),
+	}
+}
+
+// Decode unmarshals the next YAML document into the given message.
+//
+// Documents without content, such as those that contain only comments, are
+// skipped. Returns io.EOF when there are no more documents in the stream.
+func (d *Decoder) Decode(message proto.Message) error {
+	for {
+		data, lineOffset, err := d.nextDocument()
+		if err != nil {
+			return err
+		}
+		var yamlFile yaml.Node
+		if err := parseYAML(data, &yamlFile); err != nil {
+			return d.options.newSyntaxError(err, data, lineOffset)
+		}
+		if isEmptyDocument(&yamlFile) {
+			continue
+		}
+		shiftLines(&yamlFile, lineOffset)
+		return d.options.unmarshalDocument(&yamlFile, message, data, lineOffset)
+	}
+}
+
+// nextDocument reads the source of the next document from the stream, returning
+// the data and the number of lines in the stream that precede it.
+func (d *Decoder) nextDocument() ([]byte, int, error) {
+	if d.err != nil && d.pending == nil {
+		return nil, 0, d.err
+	}
+	lineOffset := d.lineCount
+	data := d.pending
+	d.pending = nil
+	if data != nil {
+		d.lineCount++
+	}
+	tooLarge := d.isTooLarge(data)
+	for d.err == nil {
+		if len(data) > 0 && d.atDocumentStart() && !isDirectivePrefix(data) {
+			d.pending, _, d.err = d.readLine(d.lineLimit(nil, false))
+			break
+		}
+		line, size, err := d.readLine(d.lineLimit(data, tooLarge))
+		d.err = err
+		if size == 0 {
+			break
+		}
+		d.lineCount++
+		if !tooLarge {
+			data = append(data, line...)
+			tooLarge = d.isTooLarge(data)
+		}
+		if isDocumentMarker(line, "...") {
+			break // The end of this document.
+		}
+	}
+	switch {
+	case tooLarge:
+		return nil, 0, d.options.newDocumentTooLargeError(data, lineOffset)
+	case len(data) == 0:
+		return nil, 0, d.err
+	case d.err != nil && !errors.Is(d.err, io.EOF):
+		return nil, 0, d.err
+	}
+	return data, lineOffset, nil
+}
+
+// atDocumentStart returns true if the next line of the stream starts a new
+// document.
+func (d *Decoder) atDocumentStart() bool {
+	prefix, _ := d.reader.Peek(len("--- "))
+	return isDocumentMarker(prefix, "---")
+}
+
+// isDirectivePrefix returns true if the given data only contains directives,
+// such as `%YAML 1.2`, with comments and blank lines. Directives belong to the
+// document that starts after them.
+func isDirectivePrefix(data []byte) bool {
+	hasDirective := false
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(line, []byte{'%'}):
+			hasDirective = true
+		case len(trimmed) > 0 && trimmed[0] != '#':
+			return false
+		}
+	}
+	return hasDirective
+}
+
+// parseYAML parses the given YAML data into the given node.
+//
+// The yaml package only accepts the `%YAML 1.1` directive, although it parses
+// the data as YAML 1.2 in most respects, so a leading `%YAML 1.2` directive is
+// read as `%YAML 1.1`, which keeps all positions.
+func parseYAML(data []byte, node *yaml.Node) error {
+	if offset := findYAML12Directive(data); offset >= 0 {
+		data = bytes.Clone(data)
+		data[offset] = '1'
+	}
+	return yaml.Unmarshal(data, node)
+}
+
+// findYAML12Directive returns the offset of the minor version of a leading
+// `%YAML 1.2` directive in the given data, or -1 if there is none.
+func findYAML12Directive(data []byte) int {
+	for offset := 0; offset < len(data); {
+		line := data[offset:]
+		if index := bytes.IndexByte(line, '\n'); index >= 0 {
+			line = line[:index+1]
+		}
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(line, []byte{'%'}):
+			if fields := bytes.Fields(line); len(fields) >= 2 && string(fields[0]) == "%YAML" && string(fields[1]) == "1.2" {
+				return offset + bytes.Index(line, fields[1]) + 2
+			}
+		case len(trimmed) > 0 && trimmed[0] != '#':
+			return -1 // The content of the document.
+		}
+		offset += len(line)
+	}
+	return -1
+}
+
+// isTooLarge returns true if the data exceeds MaxDocumentBytes.
+func (d *Decoder) isTooLarge(data []byte) bool {
+	return d.options.MaxDocumentBytes > 0 && len(data) > d.options.MaxDocumentBytes
+}
+
+// lineLimit returns the number of bytes of the next line to keep, or -1 to keep
+// the whole line.
+//
+// Once a document is too large, only enough of each line is kept to find the
+// end of the document.
+func (d *Decoder) lineLimit(data []byte, tooLarge bool) int {
+	switch {
+	case d.options.MaxDocumentBytes <= 0:
+		return -1
+	case tooLarge:
+		return len("... ")
+	default:
+		// One more byte than allowed, to detect that the limit was exceeded.
+		return d.options.MaxDocumentBytes - len(data) + 1
+	}
+}
+
+// readLine reads the next line from the stream, including the trailing newline.
+//
+// Returns at most limit bytes of the line, or the whole line if limit is
+// negative, and the size of the whole line.
+func (d *Decoder) readLine(limit int) ([]byte, int, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := d.reader.ReadSlice('\n')
+		size += len(chunk)
+		switch {
+		case limit < 0:
+			line = append(line, chunk...)
+		case len(line) < limit:
+			line = append(line, chunk[:min(len(chunk), limit-len(line))]...)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, size, err
+		}
+	}
+}
+
+// isEmptyDocument returns true if the document has no content.
+func isEmptyDocument(node *yaml.Node) bool {
+	switch {
+	case node.Kind == 0:
+		return true
+	case node.Kind == yaml.DocumentNode && len(node.Content) == 1:
+		content := node.Content[0]
+		return content.Kind == yaml.ScalarNode && isNull(content) && content.Value == "" && content.Style&yaml.TaggedStyle == 0
+	default:
+		return false
+	}
+}
+
+// isDocumentMarker returns true if the line starts with the given document
+// marker, which YAML only allows at the start of a line outside of any content.
+func isDocumentMarker(line []byte, marker string) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+	rest := line[len(marker):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n'
+}
+
+// shiftLines adds the given offset to the line numbers of the node and its
+// children.
+func shiftLines(node *yaml.Node, offset int) {
+	if offset == 0 {
+		return
+	}
+	node.Line += offset
+	for _, child := range node.Content {
+		shiftLines(child, offset)
+	}
+}
+
+// Encoder writes a stream of `---` separated YAML documents, one for each
+// Protobuf message.
+type Encoder struct {
+	options MarshalOptions
+	encoder *yaml.Encoder
+}
+
+// NewEncoder returns a new Encoder that writes to w using the given options.
+//
+// Each document is written to w as soon as it is encoded. Call Close after the
+// last message has been encoded.
+func NewEncoder(w io.Writer, options MarshalOptions) *Encoder {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(options.Indent)
+	return &Encoder{
+		options: options,
+		encoder: encoder,
+	}
+}
+
+// Encode writes the given message to the stream as a YAML document.
+func (e *Encoder) Encode(message proto.Message) error {
+	node, err := e.options.MarshalNode(message)
+	if err != nil {
+		return err
+	}
+	return e.encoder.Encode(node)
+}
+
+// Close ends the stream. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	return e.encoder.Close()
+}
@@ -0,0 +1,157 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+	data := `# Leading comment
+---
+name: first
+---
+# Only a comment.
+---
+name: second
+...
+---
+name: third
+`
+	decoder := NewDecoder(strings.NewReader(data), UnmarshalOptions{})
+	var names []string
+	for {
+		actual := &testv1.EditionsTest{}
+		err := decoder.Decode(actual)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, actual.GetName())
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, names)
+}
+
+func TestDecoderDirectives(t *testing.T) {
+	t.Parallel()
+	data := `%YAML 1.1
+---
+name: first
+...
+# The tags of the second document.
+%TAG !e! tag:example.com,2000:
+---
+name: second
+...
+%YAML 1.2
+---
+name: third
+`
+	decoder := NewDecoder(strings.NewReader(data), UnmarshalOptions{})
+	var names []string
+	for {
+		actual := &testv1.EditionsTest{}
+		err := decoder.Decode(actual)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, actual.GetName())
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, names)
+
+	single := &testv1.EditionsTest{}
+	require.NoError(t, Unmarshal([]byte("%YAML 1.2\n---\nname: single\n"), single))
+	assert.Equal(t, "single", single.GetName())
+
+	// Errors report lines relative to the start of the stream.
+	decoder = NewDecoder(strings.NewReader("name: first\n...\n%YAML 1.2\n---\nname: [\n"), UnmarshalOptions{})
+	require.NoError(t, decoder.Decode(&testv1.EditionsTest{}))
+	require.ErrorContains(t, decoder.Decode(&testv1.EditionsTest{}), ":5:")
+}
+
+func TestDecoderErrors(t *testing.T) {
+	t.Parallel()
+	data := `values:
+  - oneof_string_value: hi
+---
+values:
+  - oneof_int32_value: hi
+---
+values:
+  - oneof_string_value: bye`
+	decoder := NewDecoder(strings.NewReader(data), UnmarshalOptions{Path: "stream.yaml"})
+
+	actual := &testv1.Proto2Test{}
+	require.NoError(t, decoder.Decode(actual))
+	assert.Equal(t, "hi", actual.GetValues()[0].GetOneofStringValue())
+
+	actual = &testv1.Proto2Test{}
+	err := decoder.Decode(actual)
+	require.Error(t, err)
+	assert.Equal(t, `stream.yaml:5:24 invalid integer: invalid number, expected digit
+   5 |   - oneof_int32_value: hi
+   5 | .......................^
+`, err.Error())
+
+	actual = &testv1.Proto2Test{}
+	require.NoError(t, decoder.Decode(actual))
+	assert.Equal(t, "bye", actual.GetValues()[0].GetOneofStringValue())
+
+	require.ErrorIs(t, decoder.Decode(actual), io.EOF)
+	require.ErrorIs(t, decoder.Decode(actual), io.EOF)
+}
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+	var buffer strings.Builder
+	encoder := NewEncoder(&buffer, MarshalOptions{Indent: 2, UseProtoNames: true})
+	require.NoError(t, encoder.Encode(&testv1.Proto2Test{
+		Values: []*testv1.Proto2TestValue{
+			{OneofValue: &testv1.Proto2TestValue_OneofStringValue{OneofStringValue: "hi"}},
+		},
+	}))
+	// Each document is written as soon as it is encoded.
+	assert.Equal(t, "values:\n  - oneof_string_value: hi\n", buffer.String())
+	require.NoError(t, encoder.Encode(&testv1.EditionsTest{Name: proto.String("second")}))
+	require.NoError(t, encoder.Close())
+	assert.Equal(t, "values:\n  - oneof_string_value: hi\n---\nname: second\n", buffer.String())
+
+	// The output can be read back with a Decoder.
+	decoder := NewDecoder(strings.NewReader(buffer.String()), UnmarshalOptions{})
+	require.NoError(t, decoder.Decode(&testv1.Proto2Test{}))
+	actual := &testv1.EditionsTest{}
+	require.NoError(t, decoder.Decode(actual))
+	assert.Equal(t, "second", actual.GetName())
+	require.ErrorIs(t, decoder.Decode(actual), io.EOF)
+}
+
+func TestEncoderError(t *testing.T) {
+	t.Parallel()
+	var buffer strings.Builder
+	encoder := NewEncoder(&buffer, MarshalOptions{})
+	require.ErrorContains(t, encoder.Encode(&testv1.EditionsTest{}), "required field")
+	require.NoError(t, encoder.Encode(&testv1.EditionsTest{Name: proto.String("valid")}))
+	require.NoError(t, encoder.Close())
+	assert.Equal(t, "name: valid\n", buffer.String())
+}
@@ -0,0 +1,94 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"strings"
+
+	protoyamlv1 "buf.build/go/protoyaml/gen/proto/buf/protoyaml/v1"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// applyFieldStyle applies the style requested by the (buf.protoyaml.v1.field)
+// option of the given field to its marshaled value.
+//
+// Flow style applies to the value itself. The scalar styles apply to the
+// value of a singular field, and to the elements of a list or the values of a
+// map.
+func applyFieldStyle(node *yaml.Node, field protoreflect.FieldDescriptor) {
+	style := getFieldStyle(field)
+	switch {
+	case style == protoyamlv1.Style_STYLE_UNSPECIFIED:
+	case style == protoyamlv1.Style_STYLE_FLOW:
+		if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+			node.Style = yaml.FlowStyle
+		}
+	case field.IsList():
+		for _, item := range node.Content {
+			applyScalarStyle(item, style)
+		}
+	case field.IsMap():
+		for i := 1; i < len(node.Content); i += 2 {
+			applyScalarStyle(node.Content[i], style)
+		}
+	default:
+		applyScalarStyle(node, style)
+	}
+}
+
+// applyScalarStyle applies the given style to a string scalar. Other nodes are
+// left unchanged, so that quoting never changes how a value is parsed.
+func applyScalarStyle(node *yaml.Node, style protoyamlv1.Style) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+		return
+	}
+	switch style {
+	case protoyamlv1.Style_STYLE_LITERAL:
+		if strings.Contains(node.Value, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+	case protoyamlv1.Style_STYLE_DOUBLE_QUOTED:
+		node.Style = yaml.DoubleQuotedStyle
+	case protoyamlv1.Style_STYLE_SINGLE_QUOTED:
+		node.Style = yaml.SingleQuotedStyle
+	default:
+	}
+}
+
+// getFieldStyle returns the style requested by the (buf.protoyaml.v1.field)
+// option of the given field.
+func getFieldStyle(field protoreflect.FieldDescriptor) protoyamlv1.Style {
+	options, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok || options == nil {
+		return protoyamlv1.Style_STYLE_UNSPECIFIED
+	}
+	if !proto.HasExtension(options, protoyamlv1.E_Field) {
+		// The option is left as an unknown field if the options were parsed
+		// without the extension registered.
+		unknown := options.ProtoReflect().GetUnknown()
+		if len(unknown) == 0 {
+			return protoyamlv1.Style_STYLE_UNSPECIFIED
+		}
+		options = &descriptorpb.FieldOptions{}
+		if err := proto.Unmarshal(unknown, options); err != nil {
+			return protoyamlv1.Style_STYLE_UNSPECIFIED
+		}
+	}
+	fieldOptions, _ := proto.GetExtension(options, protoyamlv1.E_Field).(*protoyamlv1.FieldOptions)
+	return fieldOptions.GetStyle()
+}
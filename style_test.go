@@ -0,0 +1,136 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+
+	protoyamlv1 "buf.build/go/protoyaml/gen/proto/buf/protoyaml/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func newStyleTestType(t *testing.T) protoreflect.MessageType {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, fieldType descriptorpb.FieldDescriptorProto_Type, style protoyamlv1.Style) *descriptorpb.FieldDescriptorProto {
+		result := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  label.Enum(),
+			Type:   fieldType.Enum(),
+		}
+		if style != protoyamlv1.Style_STYLE_UNSPECIFIED {
+			result.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(result.Options, protoyamlv1.E_Field, &protoyamlv1.FieldOptions{Style: style})
+		}
+		return result
+	}
+	byName := field("by_name", 5, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, protoyamlv1.Style_STYLE_SINGLE_QUOTED)
+	byName.TypeName = proto.String(".test.style.Config.ByNameEntry")
+	// Descriptors parsed without the extension registered carry the option
+	// as an unknown field.
+	unparsed := field("unparsed", 7, optional, stringType, protoyamlv1.Style_STYLE_DOUBLE_QUOTED)
+	data, err := proto.Marshal(unparsed.GetOptions())
+	require.NoError(t, err)
+	unparsed.Options = &descriptorpb.FieldOptions{}
+	require.NoError(t, proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}.Unmarshal(data, unparsed.GetOptions()))
+	require.False(t, proto.HasExtension(unparsed.GetOptions(), protoyamlv1.E_Field))
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("style.proto"),
+		Package: proto.String("test.style"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Config"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("script", 1, optional, stringType, protoyamlv1.Style_STYLE_LITERAL),
+				field("summary", 2, optional, stringType, protoyamlv1.Style_STYLE_LITERAL),
+				field("tags", 3, repeated, stringType, protoyamlv1.Style_STYLE_FLOW),
+				field("name", 4, optional, stringType, protoyamlv1.Style_STYLE_DOUBLE_QUOTED),
+				byName,
+				field("count", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, protoyamlv1.Style_STYLE_DOUBLE_QUOTED),
+				unparsed,
+				field("plain", 8, optional, stringType, protoyamlv1.Style_STYLE_UNSPECIFIED),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("ByNameEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("key", 1, optional, stringType, protoyamlv1.Style_STYLE_UNSPECIFIED),
+					field("value", 2, optional, stringType, protoyamlv1.Style_STYLE_UNSPECIFIED),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return dynamicpb.NewMessageType(file.Messages().Get(0))
+}
+
+func TestMarshalFieldStyle(t *testing.T) {
+	t.Parallel()
+	msgType := newStyleTestType(t)
+	message := msgType.New().Interface()
+	require.NoError(t, Unmarshal([]byte(`script: "echo one\necho two\n"
+summary: one line
+tags: [a, b]
+name: main
+byName:
+  x: "1"
+  y: two
+count: 3
+unparsed: hidden
+plain: text
+`), message))
+	data, err := MarshalOptions{Indent: 2}.Marshal(message)
+	require.NoError(t, err)
+	assert.Equal(t, `script: |
+  echo one
+  echo two
+summary: one line
+tags: [a, b]
+name: "main"
+byName:
+  x: '1'
+  y: 'two'
+count: 3
+unparsed: "hidden"
+plain: text
+`, string(data))
+
+	roundTrip := msgType.New().Interface()
+	require.NoError(t, Unmarshal(data, roundTrip))
+	assert.True(t, proto.Equal(message, roundTrip))
+}
+
+func TestMarshalFieldStyleIgnored(t *testing.T) {
+	t.Parallel()
+	// The option has no effect on fields that are not strings, lists, or maps.
+	msgType := newStyleTestType(t)
+	message := msgType.New().Interface()
+	require.NoError(t, Unmarshal([]byte("count: 3\ntags: []\n"), message))
+	data, err := MarshalOptions{Indent: 2, EmitUnpopulated: true}.Marshal(message)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "count: 3\n")
+	assert.Contains(t, string(data), "tags: []\n")
+	assert.Contains(t, string(data), `name: ""`+"\n")
+}
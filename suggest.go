@@ -0,0 +1,142 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// findSuggestion returns the candidate that is most likely what was meant by the
+// given name, or an empty string if no candidate is close enough.
+//
+// Names are compared ignoring case, underscores and dashes, so that snake_case
+// and lowerCamelCase spellings match. Otherwise, the candidate with the
+// smallest edit distance is returned, as long as the distance is small relative
+// to the length of the name.
+func findSuggestion(name string, candidates []string) string {
+	normalized := normalizeName(name)
+	if normalized == "" {
+		return ""
+	}
+	best := ""
+	bestDistance := max(1, len([]rune(normalized))/3) + 1
+	for _, candidate := range candidates {
+		normalizedCandidate := normalizeName(candidate)
+		if normalizedCandidate == normalized {
+			return candidate
+		}
+		distance := editDistance(normalized, normalizedCandidate)
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// findFieldSuggestion returns the name of the field that was most likely meant
+// by the given key, in the same style as the key.
+func findFieldSuggestion(key string, fields protoreflect.FieldDescriptors) string {
+	names := make([]string, 0, fields.Len())
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		if strings.ContainsFunc(key, unicode.IsUpper) {
+			names = append(names, field.JSONName())
+		} else {
+			names = append(names, field.TextName())
+		}
+	}
+	return findSuggestion(key, names)
+}
+
+// findEnumSuggestion returns the name of the enum value that was most likely
+// meant by the given name.
+//
+// In addition to findSuggestion, a name matches a value if the value name ends
+// with the name, so that "UNSPECIFIED" matches "MY_ENUM_UNSPECIFIED".
+func findEnumSuggestion(name string, values protoreflect.EnumValueDescriptors) string {
+	names := make([]string, 0, values.Len())
+	var suffixMatch string
+	for i := range values.Len() {
+		valueName := string(values.Get(i).Name())
+		names = append(names, valueName)
+		if suffixMatch == "" && name != "" && strings.HasSuffix(strings.ToUpper(valueName), "_"+strings.ToUpper(name)) {
+			suffixMatch = valueName
+		}
+	}
+	if suggestion := findSuggestion(name, names); suggestion != "" {
+		return suggestion
+	}
+	return suffixMatch
+}
+
+// findTypeURLSuggestion returns the type URL that was most likely meant by the
+// given type URL, if the resolver can list its message types.
+func findTypeURLSuggestion(typeURL string, resolver protoResolver) string {
+	ranger, ok := resolver.(interface {
+		RangeMessages(f func(protoreflect.MessageType) bool)
+	})
+	if !ok {
+		return ""
+	}
+	prefix, name := "", typeURL
+	if index := strings.LastIndexByte(typeURL, '/'); index >= 0 {
+		prefix, name = typeURL[:index+1], typeURL[index+1:]
+	}
+	var names []string
+	ranger.RangeMessages(func(msgType protoreflect.MessageType) bool {
+		names = append(names, string(msgType.Descriptor().FullName()))
+		return true
+	})
+	if suggestion := findSuggestion(name, names); suggestion != "" {
+		return prefix + suggestion
+	}
+	return ""
+}
+
+// normalizeName returns the name in lower case, without underscores or dashes.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := range source {
+		current[0] = i + 1
+		for j := range target {
+			cost := 1
+			if source[i] == target[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
@@ -0,0 +1,60 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFindSuggestion(t *testing.T) {
+	t.Parallel()
+	candidates := []string{"max_retries", "min_retries", "timeout", "name"}
+	for _, testCase := range []struct {
+		Name       string
+		Suggestion string
+	}{
+		{Name: "maxRetries", Suggestion: "max_retries"},
+		{Name: "MAX-RETRIES", Suggestion: "max_retries"},
+		{Name: "max_retires", Suggestion: "max_retries"},
+		{Name: "timout", Suggestion: "timeout"},
+		{Name: "nme", Suggestion: "name"},
+		{Name: "retries", Suggestion: ""},
+		{Name: "x", Suggestion: ""},
+		{Name: "", Suggestion: ""},
+	} {
+		assert.Equal(t, testCase.Suggestion, findSuggestion(testCase.Name, candidates), testCase.Name)
+	}
+}
+
+func TestFindEnumSuggestion(t *testing.T) {
+	t.Parallel()
+	values := descriptorpb.FieldDescriptorProto_TYPE_INT32.Descriptor().Values()
+	assert.Equal(t, "TYPE_INT32", findEnumSuggestion("type_int32", values))
+	assert.Equal(t, "TYPE_INT32", findEnumSuggestion("INT32", values))
+	assert.Equal(t, "TYPE_STRING", findEnumSuggestion("string", values))
+	assert.Empty(t, findEnumSuggestion("VARCHAR", values))
+}
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 0, editDistance("abc", "abc"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+	assert.Equal(t, 1, editDistance("abc", "abd"))
+	assert.Equal(t, 2, editDistance("ab", "ba"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+}
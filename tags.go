@@ -0,0 +1,99 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"errors"
+
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TagHandler transforms YAML nodes with a custom tag, such as `!file`.
+type TagHandler interface {
+	// HandleTag returns the node to unmarshal in place of the given node, which
+	// has the tag the handler is registered for, as the value of the given
+	// field.
+	//
+	// The field is the list field for both a list and its elements, and the
+	// map's value field for map values. To produce a value directly, return a
+	// scalar node, such as a `!!binary` node for bytes.
+	//
+	// Nodes in the result without a position are reported in errors at the
+	// position of the tagged node. An error is reported at the position of the
+	// tagged node.
+	HandleTag(node *yaml.Node, field protoreflect.FieldDescriptor) (*yaml.Node, error)
+}
+
+// TagHandlerFunc is a function that implements TagHandler.
+type TagHandlerFunc func(node *yaml.Node, field protoreflect.FieldDescriptor) (*yaml.Node, error)
+
+// HandleTag calls f(node, field).
+func (f TagHandlerFunc) HandleTag(node *yaml.Node, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+	return f(node, field)
+}
+
+// handleTag returns the node to unmarshal in place of the given node, as the
+// value of the given field.
+//
+// Returns nil if the tag handler failed.
+func (u *unmarshaler) handleTag(node *yaml.Node, field protoreflect.FieldDescriptor) *yaml.Node {
+	if node.Tag == secretTag && u.options.SecretResolver != nil {
+		return u.resolveSecret(node, field)
+	}
+	handler, ok := u.options.TagHandlers[node.Tag]
+	if !ok {
+		return node
+	}
+	result, err := handler.HandleTag(node, field)
+	switch {
+	case err != nil:
+		u.addError(node, err)
+		return nil
+	case result == nil:
+		u.addError(node, errors.New("tag handler returned no node"))
+		return nil
+	}
+	return u.positionNode(result, node)
+}
+
+// positionNode returns the given node, with the position of the tagged node
+// for the nodes that do not have a position.
+func (u *unmarshaler) positionNode(node *yaml.Node, tagged *yaml.Node) *yaml.Node {
+	var content []*yaml.Node
+	for i, child := range node.Content {
+		positioned := u.positionNode(child, tagged)
+		if positioned != child && content == nil {
+			content = make([]*yaml.Node, len(node.Content))
+			copy(content, node.Content[:i])
+		}
+		if content != nil {
+			content[i] = positioned
+		}
+	}
+	if node.Line != 0 && content == nil {
+		return node
+	}
+	result := u.copyNode(node)
+	if content != nil {
+		result.Content = content
+	}
+	if node.Line == 0 {
+		result.Line = tagged.Line
+		result.Column = tagged.Column
+		u.copySources(tagged, result)
+	}
+	return result
+}
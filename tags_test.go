@@ -0,0 +1,91 @@
+// Copyright 2023-2026 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoyaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	testv1 "buf.build/go/protoyaml/internal/gen/proto/buf/protoyaml/test/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var testTagHandlers = map[string]TagHandler{
+	// Upper case strings.
+	"!upper": TagHandlerFunc(func(node *yaml.Node, _ protoreflect.FieldDescriptor) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.ToUpper(node.Value)}, nil
+	}),
+	// Bytes from a fake file.
+	"!base64file": TagHandlerFunc(func(node *yaml.Node, field protoreflect.FieldDescriptor) (*yaml.Node, error) {
+		if field.Kind() != protoreflect.BytesKind {
+			return nil, fmt.Errorf("!base64file cannot be used for %v", field.Kind())
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString([]byte("contents of " + node.Value))}, nil
+	}),
+	// A nested message, with a value from the tagged node.
+	"!nested": TagHandlerFunc(func(node *yaml.Node, _ protoreflect.FieldDescriptor) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "bb"},
+			{Kind: yaml.ScalarNode, Value: node.Value},
+		}}, nil
+	}),
+}
+
+func TestTagHandlers(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{TagHandlers: testTagHandlers}
+	data := []byte(`values:
+  - single_string: !upper hello
+    single_bytes: !base64file a.txt
+    single_nested_message: !nested 1
+    repeated_string: [a, !upper b]
+    map_string_message: {a: !nested 2}
+`)
+	actual := &testv1.Proto3Test{}
+	require.NoError(t, options.Unmarshal(data, actual))
+	value := actual.GetValues()[0]
+	assert.Equal(t, "HELLO", value.GetSingleString())
+	assert.Equal(t, []byte("contents of a.txt"), value.GetSingleBytes())
+	assert.Equal(t, int32(1), value.GetSingleNestedMessage().GetBb())
+	assert.Equal(t, []string{"a", "B"}, value.GetRepeatedString())
+	assert.Equal(t, int32(2), value.GetMapStringMessage()["a"].GetBb())
+}
+
+func TestTagHandlerErrors(t *testing.T) {
+	t.Parallel()
+	options := UnmarshalOptions{Path: "test.yaml", TagHandlers: testTagHandlers}
+	data := []byte(`values:
+  - single_string: !base64file a.txt
+    single_nested_message: !nested x
+`)
+	err := options.Unmarshal(data, &testv1.Proto3Test{})
+	var errList ErrorList
+	require.ErrorAs(t, err, &errList)
+	require.Len(t, errList, 2)
+	assert.Equal(t, `test.yaml:2:20 !base64file cannot be used for string
+   2 |   - single_string: !base64file a.txt
+   2 | ...................^
+`, errList[0].Error())
+	assert.Equal(t, "values[0].single_string", errList[0].FieldPath)
+	// Errors in the result are reported at the tagged node.
+	assert.Equal(t, 3, errList[1].Line)
+	assert.Equal(t, 28, errList[1].Column)
+	assert.Equal(t, "values[0].single_nested_message.bb", errList[1].FieldPath)
+}